@@ -1,6 +1,9 @@
 package main
 
 import (
+	"os"
+	"time"
+
 	"github.com/americanas-go/config"
 	igzap "github.com/americanas-go/log/contrib/go.uber.org/zap.v1"
 )
@@ -10,10 +13,42 @@ func loadDefaultConfig() {
 	config.Add("server.port", 8080, "Server port")
 	config.Add("server.disableStartupMessage", true, "Disable fiber startup message")
 
+	config.Add("server.tls.enabled", false, "Enable the HTTPS listener")
+	config.Add("server.tls.port", 8443, "HTTPS server port")
+	config.Add("server.tls.certFile", "", "Path to the TLS certificate file, a self-signed certificate is generated if not set")
+	config.Add("server.tls.keyFile", "", "Path to the TLS key file")
+	config.Add("server.tls.hosts", []string{"localhost", "127.0.0.1"}, "Hosts included in the generated certificate")
+	config.Add("server.tls.exportPath", "", "Folder where the generated CA and certificate are written to")
+	config.Add("server.tls.clientAuth", "none", "Client certificate authentication (none/request/require)")
+	config.Add("server.tls.clientCAFile", "", "Path to the CA file used to verify client certificates")
+
 	config.Add("health.port", 8081, "Health endpoint port (must not be the same as the server port)")
 
 	config.Add("loader.path.mapping", "files/mapping", "Path to the folder containing the mapping files")
 	config.Add("loader.path.response", "files/response", "Path to the folder containing the response files")
+	config.Add("loader.wiremock.enabled", false, "Convert WireMock stub files found in the mapping folder")
+
+	config.Add("matcher.nearMiss.candidates", 3, "Number of closest mappings listed when no match is found")
+
+	config.Add("matcher.disabledTags", []string{}, "Tags of the mappings disabled on startup")
+
+	config.Add("matcher.contract.enabled", false, "Respond to requests violating the contract of a mapping with an error instead of 404")
+	config.Add("matcher.contract.statusCode", 400, "Status code of the responses to contract violations")
+
+	config.Add("scenario.isolation.idleTimeout", 10*time.Minute, "Time without requests after which the state of an isolated scenario is discarded for a key")
+
+	config.Add("journal.maxEntries", 1000, "Maximum number of requests kept in the request journal")
+
+	config.Add("callbacks.maxResults", 1000, "Maximum number of callback results kept")
+
+	config.Add("accessLog.enabled", false, "Log every request handled, along with its match outcome")
+	config.Add("accessLog.body.enabled", false, "Include request and response bodies in the access log")
+	config.Add("accessLog.body.maxSize", 1024, "Maximum size of the bodies included in the access log, larger ones are truncated")
+
+	config.Add("tracing.enabled", false, "Enable/disable OpenTelemetry tracing")
+	config.Add("tracing.endpoint", "localhost:4318", "OTLP/HTTP endpoint the traces are exported to")
+	config.Add("tracing.insecure", true, "Export traces without TLS")
+	config.Add("tracing.serviceName", "mantis", "Service name reported in the traces")
 
 	config.Add("log.level", "INFO", "Logging level")
 	config.Add("log.format", "TEXT", "Logging format")
@@ -22,6 +57,23 @@ func loadDefaultConfig() {
 
 }
 
+// loadEnvConfig loads the configuration from the environment only, for commands whose arguments
+// are not configuration flags.
+func loadEnvConfig() {
+	loadDefaultConfig()
+	withoutArgs(config.Load)
+}
+
+// withoutArgs runs the function with the command line arguments hidden, since config.Load always parses
+// them as configuration flags. The arguments are restored once it returns.
+func withoutArgs(fn func()) {
+	args := os.Args
+	os.Args = args[:1]
+	defer func() { os.Args = args }()
+
+	fn()
+}
+
 func zapOptions() *igzap.Options {
 	return &igzap.Options{
 		Console: struct {
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/americanas-go/config"
+	"github.com/dubonzi/mantis/pkg/app"
+)
+
+// importFunc generates mappings from the content of a file in another format, named after the given name.
+type importFunc func(content []byte, name string) (app.Import, error)
+
+// importers register the flags of each format, returning its import function.
+var importers = map[string]func(flags *flag.FlagSet) importFunc{
+	"openapi": func(*flag.FlagSet) importFunc {
+		return app.ImportOpenAPI
+	},
+	"har": func(flags *flag.FlagSet) importFunc {
+		headers := flags.String("headers", "", "Comma separated request headers matched by the mappings")
+		scenarios := flags.Bool("scenarios", false, "Turn requests with different responses over time into scenarios")
+		host := flags.Bool("host", false, "Match the host the requests were captured from")
+		return func(content []byte, name string) (app.Import, error) {
+			return app.ImportHAR(content, name, app.HAROptions{Headers: splitList(*headers), Scenarios: *scenarios, Host: *host})
+		}
+	},
+}
+
+func splitList(value string) []string {
+	var values []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// importMappings generates mappings from a file in another format and writes them to the mapping folders:
+// 'mantis import <format> <file>'.
+func importMappings(args []string, out io.Writer) int {
+	loadEnvConfig()
+
+	if len(args) == 0 {
+		fmt.Fprintln(out, "usage: mantis import <format> [flags] <file>")
+		return 2
+	}
+
+	register, ok := importers[args[0]]
+	if !ok {
+		fmt.Fprintf(out, "unknown import format '%s'\n", args[0])
+		return 2
+	}
+
+	flags := flag.NewFlagSet("import "+args[0], flag.ContinueOnError)
+	flags.SetOutput(out)
+	importer := register(flags)
+	name := flags.String("name", "", "Name of the folder the mappings are written to")
+	mappingsPath := flags.String("mappings", config.String("loader.path.mapping"), "Path to the folder containing the mapping files")
+	responsesPath := flags.String("responses", config.String("loader.path.response"), "Path to the folder containing the response files")
+
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprintf(out, "usage: mantis import %s [flags] <file>\n", args[0])
+		return 2
+	}
+	file := flags.Arg(0)
+
+	content, err := os.ReadFile(file)
+	if err != nil {
+		fmt.Fprintf(out, "error reading file: %s\n", err)
+		return 1
+	}
+
+	imp, err := importer(content, *name)
+	if err != nil {
+		fmt.Fprintln(out, err)
+		return 1
+	}
+
+	written, err := app.NewImporterWithPaths(*mappingsPath, *responsesPath).Write(imp)
+	for _, path := range written {
+		fmt.Fprintf(out, "written %s\n", path)
+	}
+	if err != nil {
+		fmt.Fprintln(out, err)
+		return 1
+	}
+
+	fmt.Fprintf(out, "%d mapping(s) imported into '%s'\n", len(imp.Mappings), imp.Name)
+	return 0
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"io"
 	"os"
 	"os/signal"
 
@@ -9,8 +10,22 @@ import (
 	"go.uber.org/fx"
 )
 
+// commands run instead of the server when their name is the first argument, returning the exit status.
+var commands = map[string]func(args []string, out io.Writer) int{
+	"validate":  validate,
+	"match":     match,
+	"import":    importMappings,
+	"scenarios": scenarios,
+}
+
 func main() {
 
+	if len(os.Args) > 1 {
+		if command, ok := commands[os.Args[1]]; ok {
+			os.Exit(command(os.Args[2:], os.Stdout))
+		}
+	}
+
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt)
 
@@ -26,7 +41,9 @@ func main() {
 	}
 
 	<-stop
-	err = app.Stop(ctx)
+	stopCtx, cancel := context.WithTimeout(ctx, app.StopTimeout())
+	defer cancel()
+	err = app.Stop(stopCtx)
 	if err != nil {
 		log.Errorf("error stopping app: ", err)
 		os.Exit(1)
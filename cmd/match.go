@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/americanas-go/config"
+	"github.com/dubonzi/mantis/pkg/app"
+)
+
+type headerFlags map[string]string
+
+func (h headerFlags) String() string {
+	return fmt.Sprint(map[string]string(h))
+}
+
+func (h headerFlags) Set(value string) error {
+	key, val, ok := strings.Cut(value, ":")
+	if !ok {
+		return fmt.Errorf("header '%s' must be in the 'key:value' format", value)
+	}
+	h[strings.ToLower(strings.TrimSpace(key))] = strings.TrimSpace(val)
+	return nil
+}
+
+// match loads the mappings and prints how the request described by the arguments is matched,
+// without starting the server.
+func match(args []string, out io.Writer) int {
+	loadEnvConfig()
+
+	headers := make(headerFlags)
+	req := app.Request{Headers: headers}
+
+	flags := flag.NewFlagSet("match", flag.ContinueOnError)
+	flags.SetOutput(out)
+	flags.StringVar(&req.Method, "method", "GET", "Request method")
+	flags.StringVar(&req.Path, "path", "/", "Request path, including the query string")
+	flags.StringVar(&req.Scheme, "scheme", "http", "Request scheme")
+	flags.StringVar(&req.Host, "host", "", "Request host")
+	flags.StringVar(&req.Port, "port", "", "Request port")
+	flags.StringVar(&req.ClientCertificate, "client-cert", "", "Common name of the client certificate")
+	flags.Var(headers, "header", "Request header as 'key:value', can be repeated")
+	body := flags.String("body", "", "Request body, or '@file' to read it from a file")
+	mappingsPath := flags.String("mappings", config.String("loader.path.mapping"), "Path to the folder containing the mapping files")
+	responsesPath := flags.String("responses", config.String("loader.path.response"), "Path to the folder containing the response files")
+	asJSON := flags.Bool("json", false, "Print the result as JSON")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() > 0 {
+		flags.Usage()
+		return 2
+	}
+
+	req.Method = strings.ToUpper(req.Method)
+	req.Body = *body
+	if file, ok := strings.CutPrefix(*body, "@"); ok {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			fmt.Fprintf(out, "error reading body file: %s\n", err)
+			return 2
+		}
+		req.Body = string(content)
+	}
+
+	regexCache := app.NewRegexCache()
+	jsonPathCache := app.NewJSONPathCache()
+	matcher := app.NewMatcher(regexCache, jsonPathCache)
+	scenarioHandler := app.NewScenarioHandler(matcher)
+	loader := app.NewLoader(regexCache, jsonPathCache, scenarioHandler)
+
+	mappings, err := loader.LoadMappings(*mappingsPath, *responsesPath)
+	if err != nil {
+		fmt.Fprintf(out, "error loading mappings: %s\n", err)
+		return 2
+	}
+
+	for _, tag := range config.Strings("matcher.disabledTags") {
+		matcher.Toggles().SetTag(tag, false)
+	}
+
+	candidates := config.Int("matcher.nearMiss.candidates")
+	if candidates <= 0 {
+		candidates = app.DefaultNearMissCandidates
+	}
+
+	explanation := app.Explain(matcher, scenarioHandler, mappings, req, candidates)
+
+	if *asJSON {
+		content, _ := json.MarshalIndent(explanation, "", "  ")
+		fmt.Fprintln(out, string(content))
+	} else {
+		printExplanation(out, explanation)
+	}
+
+	if !explanation.Matched {
+		return 1
+	}
+	return 0
+}
+
+func printExplanation(out io.Writer, e app.Explanation) {
+	fmt.Fprintf(out, "%s %s\n\n", e.Request.Method, e.Request.Path)
+
+	if e.Matched {
+		fmt.Fprintf(out, "MATCHED %s [%d/%d]\n", e.Match.MappingFile, e.Match.Score, e.Match.MaxScore)
+		if e.MappingID != "" {
+			fmt.Fprintf(out, "  id:       %s\n", e.MappingID)
+		}
+		if e.MappingName != "" {
+			fmt.Fprintf(out, "  name:     %s\n", e.MappingName)
+		}
+		if e.Scenario != nil {
+			fmt.Fprintf(out, "  scenario: %s [%s -> %s]\n", e.Scenario.Name, e.Scenario.From, e.Scenario.To)
+		}
+	} else {
+		fmt.Fprintln(out, "NOT MATCHED")
+	}
+
+	if len(e.Candidates) == 0 {
+		return
+	}
+
+	fmt.Fprintln(out, "\nCandidates:")
+	for _, c := range e.Candidates {
+		fmt.Fprintf(out, "\n  %s [%d/%d]\n", c.MappingFile, c.Score, c.MaxScore)
+		for _, f := range c.Fields {
+			status := "ok  "
+			if !f.Matched {
+				status = "FAIL"
+			}
+			fmt.Fprintf(out, "    %s %s (%s): %s", status, f.Field, f.Matcher, f.Expected)
+			if f.Reason != "" {
+				fmt.Fprintf(out, " - %s", f.Reason)
+			}
+			fmt.Fprintln(out)
+		}
+	}
+}
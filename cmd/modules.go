@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 
 	"github.com/americanas-go/config"
@@ -22,6 +23,17 @@ func mainModule() fx.Option {
 		fx.Provide(
 			context.Background,
 			app.NewHandler,
+			app.NewWebSocketHandler,
+			app.NewAdminHandler,
+			app.NewImporter,
+			app.NewJournal,
+			app.NewMetrics,
+			app.NewTracing,
+			app.NewAccessLog,
+			app.NewResponseSelector,
+			app.NewCallbackDispatcher,
+			app.NewStore,
+			app.NewTLSConfig,
 			app.NewRegexCache,
 			app.NewLoader,
 			app.NewJSONPathCache,
@@ -29,9 +41,12 @@ func mainModule() fx.Option {
 			app.NewScenarioHandler,
 			func(loader *app.Loader) (app.Mappings, error) { return loader.GetMappings() },
 			fx.Annotate(app.NewResponseDelayer, fx.As(new(app.Delayer))),
-			fx.Annotate(app.NewService, fx.As(new(app.ServiceMatcher))),
+			app.NewService,
+			func(service *app.Service) app.ServiceMatcher { return service },
 		),
+		callbacksModule(),
 		serverModule(),
+		tracingModule(),
 		healthModule(),
 		fxLogger(),
 	)
@@ -39,7 +54,7 @@ func mainModule() fx.Option {
 
 func serverModule() fx.Option {
 	return fx.Invoke(
-		func(lc fx.Lifecycle, ctx context.Context, handler *app.Handler) {
+		func(lc fx.Lifecycle, ctx context.Context, handler *app.Handler, tlsConfig *tls.Config) {
 			srv := fiber.New(
 				fiber.Config{
 					AppName:               "Mantis Server",
@@ -59,6 +74,18 @@ func serverModule() fx.Option {
 								panic(fmt.Errorf("error starting mantis server: %s", err))
 							}
 						}()
+
+						if tlsConfig != nil {
+							ln, err := tls.Listen("tcp", ":"+config.String("server.tls.port"), tlsConfig)
+							if err != nil {
+								return fmt.Errorf("error starting mantis https server: %s", err)
+							}
+							go func() {
+								if err := srv.Listener(ln); err != nil {
+									panic(fmt.Errorf("error starting mantis https server: %s", err))
+								}
+							}()
+						}
 						return nil
 					},
 					OnStop: func(c context.Context) error {
@@ -73,7 +100,7 @@ func serverModule() fx.Option {
 
 func healthModule() fx.Option {
 	return fx.Invoke(
-		func(lc fx.Lifecycle, handler *app.Handler) {
+		func(lc fx.Lifecycle, handler *app.Handler, admin *app.AdminHandler, metrics *app.Metrics) {
 			srv := fiber.New(
 				fiber.Config{
 					AppName:               "Mantis Health Server",
@@ -82,6 +109,8 @@ func healthModule() fx.Option {
 			)
 
 			srv.Get("/health", handler.Health)
+			srv.Get("/metrics", metrics.Handler())
+			admin.Routes(srv.Group("/admin"))
 
 			lc.Append(
 				fx.Hook{
@@ -102,6 +131,47 @@ func healthModule() fx.Option {
 	)
 }
 
+// callbacksModule waits for the callbacks still being sent when stopping. It is registered before the servers,
+// so it stops after them and no callback is dispatched while waiting.
+func callbacksModule() fx.Option {
+	return fx.Invoke(
+		func(lc fx.Lifecycle, callbacks *app.CallbackDispatcher) {
+			lc.Append(
+				fx.Hook{
+					OnStop: func(c context.Context) error {
+						done := make(chan struct{})
+						go func() {
+							callbacks.Wait()
+							close(done)
+						}()
+
+						select {
+						case <-done:
+							return nil
+						case <-c.Done():
+							return fmt.Errorf("error waiting for callbacks: %w", c.Err())
+						}
+					},
+				},
+			)
+		},
+	)
+}
+
+func tracingModule() fx.Option {
+	return fx.Invoke(
+		func(lc fx.Lifecycle, tracing *app.Tracing) {
+			lc.Append(
+				fx.Hook{
+					OnStop: func(c context.Context) error {
+						return tracing.Shutdown(c)
+					},
+				},
+			)
+		},
+	)
+}
+
 func fxLogger() fx.Option {
 	if config.Bool("fx.log.enable") {
 		return fx.Provide()
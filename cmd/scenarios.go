@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/americanas-go/config"
+	"github.com/dubonzi/mantis/pkg/app"
+)
+
+// scenarios loads the mappings and prints the graph of the scenarios, or of the scenario named in the
+// arguments: 'mantis scenarios [flags] [scenario]'.
+func scenarios(args []string, out io.Writer) int {
+	loadEnvConfig()
+
+	flags := flag.NewFlagSet("scenarios", flag.ContinueOnError)
+	flags.SetOutput(out)
+	format := flags.String("format", app.GraphFormatDOT, "Output format: dot, mermaid or json")
+	mappingsPath := flags.String("mappings", config.String("loader.path.mapping"), "Path to the folder containing the mapping files")
+	responsesPath := flags.String("responses", config.String("loader.path.response"), "Path to the folder containing the response files")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() > 1 {
+		fmt.Fprintln(out, "usage: mantis scenarios [flags] [scenario]")
+		return 2
+	}
+	name := flags.Arg(0)
+
+	regexCache := app.NewRegexCache()
+	jsonPathCache := app.NewJSONPathCache()
+	scenarioHandler := app.NewScenarioHandler(app.NewMatcher(regexCache, jsonPathCache))
+	loader := app.NewLoader(regexCache, jsonPathCache, scenarioHandler)
+
+	if _, err := loader.LoadMappings(*mappingsPath, *responsesPath); err != nil {
+		fmt.Fprintf(out, "error loading mappings: %s\n", err)
+		return 2
+	}
+
+	graphs := scenarioHandler.Graphs()
+	if name != "" {
+		graph, ok := scenarioHandler.Graph(name)
+		if !ok {
+			fmt.Fprintf(out, "scenario '%s' not found\n", name)
+			return 1
+		}
+		graphs = []app.ScenarioGraph{graph}
+	}
+
+	if *format == app.GraphFormatJSON {
+		content, _ := json.MarshalIndent(graphs, "", "  ")
+		fmt.Fprintln(out, string(content))
+		return 0
+	}
+
+	content, err := app.FormatScenarioGraphs(graphs, *format)
+	if err != nil {
+		fmt.Fprintln(out, err)
+		return 2
+	}
+	fmt.Fprint(out, content)
+	return 0
+}
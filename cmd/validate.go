@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/americanas-go/config"
+	"github.com/dubonzi/mantis/pkg/app"
+)
+
+// validate checks the mapping files without starting the server and prints every error and warning found.
+//
+// The folders default to the configured loader paths and can be overridden by the flags or the arguments:
+// 'mantis validate [flags] [mappings folder] [responses folder]'.
+func validate(args []string, out io.Writer) int {
+	loadEnvConfig()
+
+	flags := flag.NewFlagSet("validate", flag.ContinueOnError)
+	flags.SetOutput(out)
+	mappingsPath := flags.String("mappings", config.String("loader.path.mapping"), "Path to the folder containing the mapping files")
+	responsesPath := flags.String("responses", config.String("loader.path.response"), "Path to the folder containing the response files")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() > 2 {
+		fmt.Fprintln(out, "usage: mantis validate [flags] [mappings folder] [responses folder]")
+		return 2
+	}
+
+	paths := []string{*mappingsPath, *responsesPath}
+	copy(paths, flags.Args())
+
+	regexCache := app.NewRegexCache()
+	jsonPathCache := app.NewJSONPathCache()
+	loader := app.NewLoader(regexCache, jsonPathCache, app.NewScenarioHandler(app.NewMatcher(regexCache, jsonPathCache)))
+
+	report := loader.Lint(paths[0], paths[1])
+
+	for _, issue := range report.Errors {
+		fmt.Fprintf(out, "ERROR %s\n", issue)
+	}
+	for _, issue := range report.Warnings {
+		fmt.Fprintf(out, "WARN  %s\n", issue)
+	}
+	fmt.Fprintf(out, "%d error(s), %d warning(s) in '%s'\n", len(report.Errors), len(report.Warnings), paths[0])
+
+	if !report.Valid() {
+		return 1
+	}
+	return 0
+}
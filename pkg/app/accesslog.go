@@ -0,0 +1,106 @@
+package app
+
+import (
+	"time"
+
+	"github.com/americanas-go/config"
+	"github.com/americanas-go/log"
+	"github.com/ohler55/ojg/oj"
+)
+
+const (
+	DefaultAccessLogMaxBodySize = 1024
+
+	truncatedBodySuffix = "...(truncated)"
+)
+
+// ScenarioTransition describes the scenario state change caused by a request.
+type ScenarioTransition struct {
+	Name string `json:"name"`
+	From string `json:"from"`
+	To   string `json:"to,omitempty"`
+}
+
+// AccessLog logs every request handled by Mantis through the configured logger.
+type AccessLog struct {
+	enabled     bool
+	logBody     bool
+	maxBodySize int
+}
+
+func NewAccessLog() *AccessLog {
+	maxBodySize := config.Int("accessLog.body.maxSize")
+	if maxBodySize <= 0 {
+		maxBodySize = DefaultAccessLogMaxBodySize
+	}
+
+	return &AccessLog{
+		enabled:     config.Bool("accessLog.enabled"),
+		logBody:     config.Bool("accessLog.body.enabled"),
+		maxBodySize: maxBodySize,
+	}
+}
+
+// Fields builds the fields logged for the request and its result.
+func (a *AccessLog) Fields(r Request, res MatchResult, latency time.Duration) log.Fields {
+	fields := log.Fields{
+		"id":      r.ID,
+		"method":  r.Method,
+		"path":    r.Path,
+		"status":  res.StatusCode,
+		"matched": res.Matched,
+		"latency": latency.String(),
+		"delay":   res.Delay.String(),
+	}
+
+	if res.Matched {
+		fields["mappingFile"] = res.Headers["X-Mapping-File"]
+		if id, ok := res.Headers[MappingIDHeader]; ok {
+			fields["mappingId"] = id
+		}
+		if res.MappingName != "" {
+			fields["mappingName"] = res.MappingName
+		}
+	}
+
+	if res.Scenario != nil {
+		fields["scenario"] = res.Scenario
+	}
+
+	if len(res.Transitions) > 0 {
+		fields["transitions"] = res.Transitions
+	}
+
+	if a.logBody {
+		fields["requestBody"] = a.truncate(r.Body)
+		fields["responseBody"] = a.truncate(responseBody(res))
+	}
+
+	return fields
+}
+
+func (a *AccessLog) Log(r Request, res MatchResult, latency time.Duration) {
+	if !a.enabled {
+		return
+	}
+
+	log.WithFields(a.Fields(r, res, latency)).Info("request handled")
+}
+
+func (a *AccessLog) truncate(body string) string {
+	if len(body) <= a.maxBodySize {
+		return body
+	}
+	return body[:a.maxBodySize] + truncatedBodySuffix
+}
+
+func responseBody(res MatchResult) string {
+	switch b := res.Body.(type) {
+	case nil:
+		return ""
+	case string:
+		return b
+	default:
+		return oj.JSON(b)
+	}
+}
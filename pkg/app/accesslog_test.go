@@ -0,0 +1,66 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/americanas-go/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccessLogFields(t *testing.T) {
+	tests := []struct {
+		name      string
+		accessLog *AccessLog
+		request   Request
+		result    MatchResult
+		want      log.Fields
+	}{
+		{
+			name:      "Should log matched request with scenario transition and delay",
+			accessLog: &AccessLog{enabled: true, maxBodySize: 10},
+			request:   Request{ID: "1", Method: "POST", Path: "/cart", Body: "{}"},
+			result: MatchResult{
+				StatusCode: 201,
+				Matched:    true,
+				Headers:    map[string]string{"X-Mapping-File": "cart.json"},
+				Scenario:   &ScenarioTransition{Name: "cart", From: "empty", To: "full"},
+				Delay:      time.Second,
+			},
+			want: log.Fields{
+				"id":          "1",
+				"method":      "POST",
+				"path":        "/cart",
+				"status":      201,
+				"matched":     true,
+				"latency":     "5ms",
+				"delay":       "1s",
+				"mappingFile": "cart.json",
+				"scenario":    &ScenarioTransition{Name: "cart", From: "empty", To: "full"},
+			},
+		},
+		{
+			name:      "Should log unmatched request with truncated bodies",
+			accessLog: &AccessLog{enabled: true, logBody: true, maxBodySize: 10},
+			request:   Request{ID: "2", Method: "POST", Path: "/order", Body: `{"order": "123456"}`},
+			result:    MatchResult{StatusCode: 404, Headers: map[string]string{"X-Mapping-File": "order.json"}, Body: "not found"},
+			want: log.Fields{
+				"id":           "2",
+				"method":       "POST",
+				"path":         "/order",
+				"status":       404,
+				"matched":      false,
+				"latency":      "5ms",
+				"delay":        "0s",
+				"requestBody":  `{"order": ` + truncatedBodySuffix,
+				"responseBody": "not found",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.accessLog.Fields(tt.request, tt.result, 5*time.Millisecond))
+		})
+	}
+}
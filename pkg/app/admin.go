@@ -0,0 +1,230 @@
+package app
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/ohler55/ojg/oj"
+)
+
+type NearMissReport struct {
+	Request    Request     `json:"request"`
+	Candidates []MatchDiff `json:"candidates"`
+}
+
+type AdminHandler struct {
+	service   *Service
+	journal   *Journal
+	selector  *ResponseSelector
+	callbacks *CallbackDispatcher
+	store     *Store
+	importer  *Importer
+}
+
+func NewAdminHandler(service *Service, journal *Journal, selector *ResponseSelector, callbacks *CallbackDispatcher, store *Store, importer *Importer) *AdminHandler {
+	return &AdminHandler{service, journal, selector, callbacks, store, importer}
+}
+
+// Routes registers the admin endpoints in the given router.
+func (h *AdminHandler) Routes(router fiber.Router) {
+	router.Get("/requests", h.Requests)
+	router.Delete("/requests", h.ResetRequests)
+	router.Get("/requests/near-misses", h.NearMisses)
+	router.Delete("/responses/counters", h.ResetResponseCounters)
+	router.Get("/mappings", h.Mappings)
+	router.Post("/mappings/:id/enable", h.EnableMapping)
+	router.Post("/mappings/:id/disable", h.DisableMapping)
+	router.Post("/tags/:tag/enable", h.EnableTag)
+	router.Post("/tags/:tag/disable", h.DisableTag)
+	router.Get("/callbacks", h.Callbacks)
+	router.Delete("/callbacks", h.ResetCallbacks)
+	router.Get("/stores", h.Stores)
+	router.Delete("/stores", h.ResetStores)
+	router.Get("/stores/:name", h.StoreResources)
+	router.Delete("/stores/:name", h.ResetStore)
+	router.Get("/scenarios", h.Scenarios)
+	router.Get("/scenarios/:name", h.Scenario)
+	router.Post("/import/openapi", h.ImportOpenAPI)
+	router.Post("/import/har", h.ImportHAR)
+}
+
+// Requests returns the journaled requests, only the unmatched ones if the 'unmatched' query param is true.
+func (h *AdminHandler) Requests(c *fiber.Ctx) error {
+	if c.QueryBool("unmatched") {
+		return sendJSON(c, h.journal.Unmatched())
+	}
+	return sendJSON(c, h.journal.Entries())
+}
+
+func (h *AdminHandler) ResetRequests(c *fiber.Ctx) error {
+	h.journal.Reset()
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// NearMisses returns, for every unmatched request in the journal, the mappings that came closer to matching it.
+func (h *AdminHandler) NearMisses(c *fiber.Ctx) error {
+	unmatched := h.journal.Unmatched()
+
+	reports := make([]NearMissReport, 0, len(unmatched))
+	for _, e := range unmatched {
+		reports = append(reports, NearMissReport{
+			Request:    e.Request,
+			Candidates: h.service.NearMisses(e.Request),
+		})
+	}
+
+	return sendJSON(c, reports)
+}
+
+// ResetResponseCounters starts the response sequences of all mappings over.
+func (h *AdminHandler) ResetResponseCounters(c *fiber.Ctx) error {
+	h.selector.Reset()
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// Mappings returns the loaded mappings, only the ones with a tag if the 'tag' query param is set.
+func (h *AdminHandler) Mappings(c *fiber.Ctx) error {
+	return sendJSON(c, h.service.Mappings(c.Query("tag")))
+}
+
+func (h *AdminHandler) EnableMapping(c *fiber.Ctx) error {
+	return h.setMappingEnabled(c, true)
+}
+
+func (h *AdminHandler) DisableMapping(c *fiber.Ctx) error {
+	return h.setMappingEnabled(c, false)
+}
+
+func (h *AdminHandler) setMappingEnabled(c *fiber.Ctx, enabled bool) error {
+	if !h.service.SetMappingEnabled(utils.CopyString(c.Params("id")), enabled) {
+		return c.SendStatus(fiber.StatusNotFound)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (h *AdminHandler) EnableTag(c *fiber.Ctx) error {
+	h.service.SetTagEnabled(utils.CopyString(c.Params("tag")), true)
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (h *AdminHandler) DisableTag(c *fiber.Ctx) error {
+	h.service.SetTagEnabled(utils.CopyString(c.Params("tag")), false)
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// Callbacks returns the results of the callbacks fired so far.
+func (h *AdminHandler) Callbacks(c *fiber.Ctx) error {
+	return sendJSON(c, h.callbacks.Results())
+}
+
+func (h *AdminHandler) ResetCallbacks(c *fiber.Ctx) error {
+	h.callbacks.Reset()
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// Stores lists the stores holding resources written by the mappings.
+func (h *AdminHandler) Stores(c *fiber.Ctx) error {
+	return sendJSON(c, h.store.Stores())
+}
+
+func (h *AdminHandler) ResetStores(c *fiber.Ctx) error {
+	h.store.ResetAll()
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// StoreResources returns the resources of the store by key.
+func (h *AdminHandler) StoreResources(c *fiber.Ctx) error {
+	resources, ok := h.store.Resources(c.Params("name"))
+	if !ok {
+		return c.SendStatus(fiber.StatusNotFound)
+	}
+	return sendJSON(c, resources)
+}
+
+func (h *AdminHandler) ResetStore(c *fiber.Ctx) error {
+	h.store.Reset(c.Params("name"))
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// Scenarios returns the graph of every scenario, as JSON or in the format of the 'format' query param,
+// 'dot' or 'mermaid'.
+func (h *AdminHandler) Scenarios(c *fiber.Ctx) error {
+	graphs := h.service.ScenarioGraphs()
+	format := c.Query("format", GraphFormatJSON)
+	if format == GraphFormatJSON {
+		return sendJSON(c, graphs)
+	}
+	return sendGraphs(c, graphs, format)
+}
+
+// Scenario returns the graph of the scenario, in the same formats as Scenarios.
+func (h *AdminHandler) Scenario(c *fiber.Ctx) error {
+	name, err := url.PathUnescape(c.Params("name"))
+	if err != nil {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+
+	graph, ok := h.service.ScenarioGraph(name)
+	if !ok {
+		return c.SendStatus(fiber.StatusNotFound)
+	}
+
+	format := c.Query("format", GraphFormatJSON)
+	if format == GraphFormatJSON {
+		return sendJSON(c, graph)
+	}
+	return sendGraphs(c, []ScenarioGraph{graph}, format)
+}
+
+func sendGraphs(c *fiber.Ctx, graphs []ScenarioGraph, format string) error {
+	content, err := FormatScenarioGraphs(graphs, format)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	c.Context().SetContentType(fiber.MIMETextPlainCharsetUTF8)
+	return c.SendString(content)
+}
+
+// ImportOpenAPI generates mappings from the OpenAPI document in the request body and writes them to the
+// mapping folders, named after the 'name' query param or the document title. They are loaded on the next start.
+func (h *AdminHandler) ImportOpenAPI(c *fiber.Ctx) error {
+	imp, err := ImportOpenAPI(c.Body(), utils.CopyString(c.Query("name")))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	return h.writeImport(c, imp)
+}
+
+// ImportHAR generates mappings from the HAR file in the request body and writes them to the mapping folders,
+// named after the 'name' query param. The 'headers' query param lists the request headers matched by the mappings,
+// 'scenarios' turns requests with different responses over time into scenarios and 'host' matches the captured host.
+func (h *AdminHandler) ImportHAR(c *fiber.Ctx) error {
+	opts := HAROptions{Scenarios: c.QueryBool("scenarios"), Host: c.QueryBool("host")}
+	for _, header := range strings.Split(c.Query("headers"), ",") {
+		if header = strings.TrimSpace(header); header != "" {
+			opts.Headers = append(opts.Headers, utils.CopyString(header))
+		}
+	}
+
+	imp, err := ImportHAR(c.Body(), utils.CopyString(c.Query("name")), opts)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	return h.writeImport(c, imp)
+}
+
+func (h *AdminHandler) writeImport(c *fiber.Ctx, imp Import) error {
+	written, err := h.importer.Write(imp)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	c.Status(fiber.StatusCreated)
+	return sendJSON(c, written)
+}
+
+func sendJSON(c *fiber.Ctx, v any) error {
+	c.Context().SetContentType(fiber.MIMEApplicationJSON)
+	return c.SendString(oj.JSON(v))
+}
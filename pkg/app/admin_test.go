@@ -0,0 +1,352 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/ohler55/ojg/alt"
+	"github.com/ohler55/ojg/oj"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestAdmin(t *testing.T, mappings Mappings) (*fiber.App, *Service, *Journal) {
+	t.Helper()
+	return newTestAdminWithImporter(t, mappings, NewImporterWithPaths(t.TempDir(), t.TempDir()))
+}
+
+func newTestAdminWithImporter(t *testing.T, mappings Mappings, importer *Importer) (*fiber.App, *Service, *Journal) {
+	t.Helper()
+	matcher := newTestMatcher(mappings)
+	journal := NewJournal()
+	selector := NewResponseSelector()
+	callbacks := NewCallbackDispatcher(&mockDelayer{})
+	store := NewStore()
+	service := NewService(mappings, matcher, NewScenarioHandler(matcher), &mockDelayer{}, selector, callbacks, store, journal, NewMetrics(mappings, nil), NewNoopTracing())
+
+	app := fiber.New()
+	NewAdminHandler(service, journal, selector, callbacks, store, importer).Routes(app.Group("/admin"))
+	return app, service, journal
+}
+
+func TestAdminRequests(t *testing.T) {
+	app, service, journal := newTestAdmin(t, getMappings())
+
+	service.MatchRequest(context.Background(), Request{ID: "1", Method: "GET", Path: "/simple"})
+	service.MatchRequest(context.Background(), Request{ID: "2", Method: "GET", Path: "/nothing"})
+
+	tests := []struct {
+		name    string
+		target  string
+		wantIDs []string
+	}{
+		{name: "Should return all requests", target: "/admin/requests", wantIDs: []string{"1", "2"}},
+		{name: "Should return unmatched requests", target: "/admin/requests?unmatched=true", wantIDs: []string{"2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := app.Test(httptest.NewRequest("GET", tt.target, nil))
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusOK, res.StatusCode)
+
+			body, err := oj.Load(res.Body)
+			require.NoError(t, err)
+			var entries []JournalEntry
+			_, err = alt.Recompose(body, &entries)
+			require.NoError(t, err)
+
+			ids := make([]string, 0)
+			for _, e := range entries {
+				ids = append(ids, e.Request.ID)
+			}
+			assert.Equal(t, tt.wantIDs, ids)
+		})
+	}
+
+	res, err := app.Test(httptest.NewRequest("DELETE", "/admin/requests", nil))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, res.StatusCode)
+	assert.Empty(t, journal.Entries())
+}
+
+func TestAdminNearMisses(t *testing.T) {
+	app, service, _ := newTestAdmin(t, getMappings())
+
+	service.MatchRequest(context.Background(), Request{ID: "1", Method: "GET", Path: "/simple"})
+	service.MatchRequest(context.Background(), Request{ID: "2", Method: "POST", Path: "/order", Headers: map[string]string{"authorization": "Bearer NotMe"}, Body: `{"cart": "777"}`})
+
+	res, err := app.Test(httptest.NewRequest("GET", "/admin/requests/near-misses", nil))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	body, err := oj.Load(res.Body)
+	require.NoError(t, err)
+	var reports []NearMissReport
+	_, err = alt.Recompose(body, &reports)
+	require.NoError(t, err)
+
+	require.Len(t, reports, 1)
+	assert.Equal(t, "2", reports[0].Request.ID)
+	require.Len(t, reports[0].Candidates, 2)
+	assert.Equal(t, "file_9", reports[0].Candidates[0].MappingFile)
+	assert.Equal(t, "file_8", reports[0].Candidates[1].MappingFile)
+	assert.Len(t, reports[0].Candidates[1].Failed(), 2)
+}
+
+func TestAdminMappings(t *testing.T) {
+	mappings := make(Mappings)
+	_ = mappings.PutAll([]Mapping{
+		{
+			ID:       "list-users",
+			Name:     "List users",
+			Tags:     []string{"users"},
+			Request:  RequestMapping{Method: "GET", Path: CommonMatch{Exact: "/users"}},
+			Response: ResponseMapping{StatusCode: 200},
+			MaxScore: 1,
+			FilePath: "users.json",
+		},
+		{
+			ID:       "list-orders",
+			Tags:     []string{"orders", "slow"},
+			Request:  RequestMapping{Method: "GET", Path: CommonMatch{Exact: "/orders"}},
+			Response: ResponseMapping{StatusCode: 200},
+			MaxScore: 1,
+			FilePath: "orders.json",
+		},
+	})
+	app, service, _ := newTestAdmin(t, mappings)
+
+	send := func(method, target string) *http.Response {
+		res, err := app.Test(httptest.NewRequest(method, target, nil))
+		require.NoError(t, err)
+		return res
+	}
+	list := func(target string) []MappingSummary {
+		body, err := oj.Load(send("GET", target).Body)
+		require.NoError(t, err)
+		var summaries []MappingSummary
+		_, err = alt.Recompose(body, &summaries)
+		require.NoError(t, err)
+		return summaries
+	}
+	matched := func(path string) bool {
+		return service.MatchRequest(context.Background(), Request{Method: "GET", Path: path}).Matched
+	}
+
+	t.Run("Should list mappings", func(t *testing.T) {
+		assert.Equal(t, []MappingSummary{
+			{ID: "list-orders", Tags: []string{"orders", "slow"}, Method: "GET", MappingFile: "orders.json", Enabled: true},
+			{ID: "list-users", Name: "List users", Tags: []string{"users"}, Method: "GET", MappingFile: "users.json", Enabled: true},
+		}, list("/admin/mappings"))
+	})
+
+	t.Run("Should list mappings by tag", func(t *testing.T) {
+		summaries := list("/admin/mappings?tag=users")
+		require.Len(t, summaries, 1)
+		assert.Equal(t, "list-users", summaries[0].ID)
+	})
+
+	t.Run("Should disable and enable mapping by id", func(t *testing.T) {
+		assert.Equal(t, http.StatusNoContent, send("POST", "/admin/mappings/list-users/disable").StatusCode)
+		assert.False(t, matched("/users"))
+		assert.True(t, matched("/orders"))
+
+		assert.Equal(t, http.StatusNoContent, send("POST", "/admin/mappings/list-users/enable").StatusCode)
+		assert.True(t, matched("/users"))
+	})
+
+	t.Run("Should return not found for unknown mapping id", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, send("POST", "/admin/mappings/unknown/disable").StatusCode)
+	})
+
+	t.Run("Should disable and enable mappings by tag", func(t *testing.T) {
+		assert.Equal(t, http.StatusNoContent, send("POST", "/admin/tags/slow/disable").StatusCode)
+		assert.False(t, matched("/orders"))
+		assert.True(t, matched("/users"))
+		assert.False(t, list("/admin/mappings?tag=slow")[0].Enabled)
+
+		assert.Equal(t, http.StatusNoContent, send("POST", "/admin/tags/slow/enable").StatusCode)
+		assert.True(t, matched("/orders"))
+	})
+}
+
+func TestAdminStores(t *testing.T) {
+	app, service, _ := newTestAdmin(t, newStoreMappings(t))
+
+	send := func(method, target string) (int, string) {
+		res, err := app.Test(httptest.NewRequest(method, target, nil))
+		require.NoError(t, err)
+		body, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		return res.StatusCode, string(body)
+	}
+
+	service.MatchRequest(context.Background(), Request{Method: "POST", Path: "/users", Body: `{"id": "1"}`})
+	service.MatchRequest(context.Background(), Request{Method: "POST", Path: "/orders", Body: `{"number": 7}`})
+
+	t.Run("Should list the stores", func(t *testing.T) {
+		status, body := send("GET", "/admin/stores")
+		assert.Equal(t, http.StatusOK, status)
+		assert.JSONEq(t, `[{"name": "orders", "resources": 1}, {"name": "users", "resources": 1}]`, body)
+	})
+
+	t.Run("Should return the resources of a store", func(t *testing.T) {
+		status, body := send("GET", "/admin/stores/users")
+		assert.Equal(t, http.StatusOK, status)
+		assert.JSONEq(t, `{"1": {"id": "1"}}`, body)
+
+		status, _ = send("GET", "/admin/stores/products")
+		assert.Equal(t, http.StatusNotFound, status)
+	})
+
+	t.Run("Should reset a store", func(t *testing.T) {
+		status, _ := send("DELETE", "/admin/stores/users")
+		assert.Equal(t, http.StatusNoContent, status)
+
+		res := service.MatchRequest(context.Background(), Request{Method: "GET", Path: "/users/1"})
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+
+		_, body := send("GET", "/admin/stores")
+		assert.JSONEq(t, `[{"name": "orders", "resources": 1}]`, body)
+	})
+
+	t.Run("Should reset every store", func(t *testing.T) {
+		status, _ := send("DELETE", "/admin/stores")
+		assert.Equal(t, http.StatusNoContent, status)
+
+		_, body := send("GET", "/admin/stores")
+		assert.JSONEq(t, `[]`, body)
+	})
+}
+
+func TestAdminImportOpenAPI(t *testing.T) {
+	mappingsPath, responsesPath := t.TempDir(), t.TempDir()
+	app, _, _ := newTestAdminWithImporter(t, make(Mappings), NewImporterWithPaths(mappingsPath, responsesPath))
+
+	t.Run("Should write the imported mappings", func(t *testing.T) {
+		spec, err := os.ReadFile("testdata/openapi/petstore.yaml")
+		require.NoError(t, err)
+
+		res, err := app.Test(httptest.NewRequest("POST", "/admin/import/openapi?name=pets", bytes.NewReader(spec)))
+		require.NoError(t, err)
+
+		assert.Equal(t, http.StatusCreated, res.StatusCode)
+		assert.FileExists(t, filepath.Join(mappingsPath, "pets", "listpets.json"))
+		assert.FileExists(t, filepath.Join(responsesPath, "pets", "listpets_response.json"))
+	})
+
+	t.Run("Should reject invalid documents", func(t *testing.T) {
+		res, err := app.Test(httptest.NewRequest("POST", "/admin/import/openapi", strings.NewReader(`{"swagger": "2.0"}`)))
+		require.NoError(t, err)
+
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+
+	t.Run("Should reject names outside of the mapping folders", func(t *testing.T) {
+		spec, err := os.ReadFile("testdata/openapi/petstore.yaml")
+		require.NoError(t, err)
+
+		res, err := app.Test(httptest.NewRequest("POST", "/admin/import/openapi?name=..%2F..%2F..", bytes.NewReader(spec)))
+		require.NoError(t, err)
+
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+		assert.NoFileExists(t, filepath.Join(mappingsPath, "..", "..", "..", "listpets.json"))
+	})
+}
+
+func TestAdminImportHAR(t *testing.T) {
+	mappingsPath, responsesPath := t.TempDir(), t.TempDir()
+	app, _, _ := newTestAdminWithImporter(t, make(Mappings), NewImporterWithPaths(mappingsPath, responsesPath))
+
+	t.Run("Should write the imported mappings", func(t *testing.T) {
+		har, err := os.ReadFile("testdata/har/session.har")
+		require.NoError(t, err)
+
+		res, err := app.Test(httptest.NewRequest("POST", "/admin/import/har?name=cart&headers=x-tenant&scenarios=true", bytes.NewReader(har)))
+		require.NoError(t, err)
+
+		assert.Equal(t, http.StatusCreated, res.StatusCode)
+		assert.FileExists(t, filepath.Join(mappingsPath, "cart", "get_api_cart_1.json"))
+		assert.FileExists(t, filepath.Join(mappingsPath, "cart", "get_api_cart_2.json"))
+		assert.FileExists(t, filepath.Join(responsesPath, "cart", "get_api_cart_2_response.json"))
+	})
+
+	t.Run("Should reject invalid files", func(t *testing.T) {
+		res, err := app.Test(httptest.NewRequest("POST", "/admin/import/har", strings.NewReader(`{`)))
+		require.NoError(t, err)
+
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+
+	t.Run("Should reject names outside of the mapping folders", func(t *testing.T) {
+		har, err := os.ReadFile("testdata/har/session.har")
+		require.NoError(t, err)
+
+		res, err := app.Test(httptest.NewRequest("POST", "/admin/import/har?name=..%2F..%2F..", bytes.NewReader(har)))
+		require.NoError(t, err)
+
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+		assert.NoFileExists(t, filepath.Join(mappingsPath, "..", "..", "..", "get_api_cart_1.json"))
+	})
+}
+
+func TestAdminScenarios(t *testing.T) {
+	app, service, _ := newTestAdmin(t, getMappings())
+	for _, m := range validScenarios["secondScenario"] {
+		service.scenarioHandler.AddScenario(m)
+	}
+
+	send := func(target string) (int, string, string) {
+		res, err := app.Test(httptest.NewRequest("GET", target, nil))
+		require.NoError(t, err)
+		body, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		return res.StatusCode, res.Header.Get("Content-Type"), string(body)
+	}
+
+	t.Run("Should list the scenario graphs", func(t *testing.T) {
+		status, contentType, body := send("/admin/scenarios")
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, fiber.MIMEApplicationJSON, contentType)
+		assert.JSONEq(t, `[{
+			"name": "Second Scenario",
+			"startingState": "Create Object",
+			"currentState": "Create Object",
+			"states": [
+				{"name": "Create Object", "starting": true, "current": true, "unreachable": false, "deadEnd": false},
+				{"name": "Object Created", "starting": false, "current": false, "unreachable": false, "deadEnd": true}
+			],
+			"transitions": [
+				{"from": "Create Object", "to": "Object Created", "label": "POST /objects", "mappingFile": "scenario2_1"}
+			]
+		}]`, body)
+	})
+
+	t.Run("Should return the graph of a scenario in the requested format", func(t *testing.T) {
+		status, contentType, body := send("/admin/scenarios/Second%20Scenario?format=dot")
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, fiber.MIMETextPlainCharsetUTF8, contentType)
+		assert.Contains(t, body, `"Create Object" -> "Object Created" [label="POST /objects"];`)
+
+		status, _, body = send("/admin/scenarios/Second%20Scenario?format=mermaid")
+		assert.Equal(t, http.StatusOK, status)
+		assert.Contains(t, body, "s0 --> s1 : POST /objects")
+	})
+
+	t.Run("Should reject unknown scenarios and formats", func(t *testing.T) {
+		status, _, _ := send("/admin/scenarios/Unknown")
+		assert.Equal(t, http.StatusNotFound, status)
+
+		status, _, _ = send("/admin/scenarios?format=svg")
+		assert.Equal(t, http.StatusBadRequest, status)
+	})
+}
@@ -0,0 +1,298 @@
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/americanas-go/config"
+	"github.com/americanas-go/log"
+	"github.com/google/uuid"
+	"github.com/ohler55/ojg/jp"
+	"github.com/ohler55/ojg/oj"
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultCallbackTimeout    = 10 * time.Second
+	DefaultCallbackMaxResults = 1000
+
+	CallbackMethodMessage   = "Callback method is required"
+	CallbackURLMessage      = "Callback URL is required"
+	CallbackRetriesMessage  = "Callback retries must not be negative"
+	CallbackTemplateMessage = "Callback template is invalid: %s"
+)
+
+// CallbackMapping defines an HTTP request fired after the mapping is matched.
+//
+// When template is true, the URL, header values and body are rendered as Go templates
+// with the matched request available as '.Request'.
+type CallbackMapping struct {
+	Method        string            `json:"method"`
+	URL           string            `json:"url"`
+	Headers       map[string]string `json:"headers,omitempty"`
+	Body          string            `json:"body,omitempty"`
+	Template      bool              `json:"template,omitempty"`
+	Delay         Delay             `json:"delay,omitempty"`
+	Retries       int               `json:"retries,omitempty"`
+	RetryInterval Duration          `json:"retryInterval,omitempty"`
+}
+
+func (c CallbackMapping) Validate(index int) ValidationErrors {
+	errs := make(ValidationErrors, 0)
+	field := fmt.Sprintf("Callbacks[%d]", index)
+
+	if c.Method == "" {
+		errs = append(errs, ValidationError{field + ".Method", CallbackMethodMessage})
+	}
+	if c.URL == "" {
+		errs = append(errs, ValidationError{field + ".URL", CallbackURLMessage})
+	}
+	if c.Retries < 0 {
+		errs = append(errs, ValidationError{field + ".Retries", CallbackRetriesMessage})
+	}
+
+	if c.Template {
+		for _, t := range c.templates() {
+			if _, err := parseTemplate(t); err != nil {
+				errs = append(errs, ValidationError{field, fmt.Sprintf(CallbackTemplateMessage, err)})
+			}
+		}
+	}
+
+	return errs
+}
+
+func (c CallbackMapping) templates() []string {
+	templates := []string{c.URL, c.Body}
+	for _, v := range c.Headers {
+		templates = append(templates, v)
+	}
+	return templates
+}
+
+type CallbackResult struct {
+	ID          string            `json:"id"`
+	RequestID   string            `json:"requestId"`
+	MappingFile string            `json:"mappingFile"`
+	Method      string            `json:"method"`
+	URL         string            `json:"url"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	Body        string            `json:"body,omitempty"`
+	Attempts    int               `json:"attempts"`
+	StatusCode  int               `json:"statusCode,omitempty"`
+	Error       string            `json:"error,omitempty"`
+	Success     bool              `json:"success"`
+	Date        string            `json:"date"`
+}
+
+// CallbackDispatcher fires the callbacks of matched mappings in the background, keeping their results.
+type CallbackDispatcher struct {
+	client  *http.Client
+	delayer Delayer
+	wg      sync.WaitGroup
+
+	mu         sync.RWMutex
+	results    []CallbackResult
+	maxResults int
+}
+
+func NewCallbackDispatcher(delayer Delayer) *CallbackDispatcher {
+	maxResults := config.Int("callbacks.maxResults")
+	if maxResults <= 0 {
+		maxResults = DefaultCallbackMaxResults
+	}
+
+	return &CallbackDispatcher{
+		client:     &http.Client{Timeout: DefaultCallbackTimeout},
+		delayer:    delayer,
+		results:    make([]CallbackResult, 0),
+		maxResults: maxResults,
+	}
+}
+
+// Dispatch fires every callback of the mapping asynchronously.
+func (d *CallbackDispatcher) Dispatch(mapping Mapping, r Request) {
+	for _, c := range mapping.Callbacks {
+		d.wg.Add(1)
+		go func(c CallbackMapping) {
+			defer d.wg.Done()
+			d.delayer.Apply(&c.Delay)
+			d.record(d.send(c, mapping.FilePath, r))
+		}(c)
+	}
+}
+
+// Wait blocks until all dispatched callbacks are done.
+func (d *CallbackDispatcher) Wait() {
+	d.wg.Wait()
+}
+
+func (d *CallbackDispatcher) Results() []CallbackResult {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	results := make([]CallbackResult, len(d.results))
+	copy(results, d.results)
+	return results
+}
+
+func (d *CallbackDispatcher) Reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.results = make([]CallbackResult, 0)
+}
+
+// record keeps the result of a callback, discarding the oldest one if there are too many.
+func (d *CallbackDispatcher) record(result CallbackResult) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if len(d.results) >= d.maxResults {
+		d.results = d.results[1:]
+	}
+	d.results = append(d.results, result)
+}
+
+func (d *CallbackDispatcher) send(c CallbackMapping, mappingFile string, r Request) CallbackResult {
+	result := CallbackResult{
+		ID:          uuid.NewString(),
+		RequestID:   r.ID,
+		MappingFile: mappingFile,
+		Method:      c.Method,
+	}
+
+	rendered, err := renderCallback(c, r)
+	if err != nil {
+		result.Error = err.Error()
+		result.Date = time.Now().Format(time.RFC3339Nano)
+		return result
+	}
+	result.URL, result.Headers, result.Body = rendered.URL, rendered.Headers, rendered.Body
+
+	for attempt := 1; ; attempt++ {
+		result.Attempts = attempt
+		result.StatusCode, err = d.do(rendered)
+		if err == nil && result.StatusCode >= http.StatusOK && result.StatusCode < http.StatusMultipleChoices {
+			result.Success = true
+			result.Error = ""
+			break
+		}
+
+		if err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Error = fmt.Sprintf("callback returned status %d", result.StatusCode)
+		}
+
+		// only errors and 5xx statuses are retried, the request won't succeed if sent again otherwise
+		if attempt > c.Retries || (err == nil && result.StatusCode < http.StatusInternalServerError) {
+			break
+		}
+		time.Sleep(time.Duration(c.RetryInterval))
+	}
+	result.Date = time.Now().Format(time.RFC3339Nano)
+
+	if !result.Success {
+		log.WithFields(log.Fields{"callback": result}).Warn("callback failed")
+	}
+
+	return result
+}
+
+func (d *CallbackDispatcher) do(c CallbackMapping) (int, error) {
+	req, err := http.NewRequest(c.Method, c.URL, strings.NewReader(c.Body))
+	if err != nil {
+		return 0, errors.Wrap(err, "error building callback request")
+	}
+	for k, v := range c.Headers {
+		req.Header.Set(k, v)
+	}
+
+	res, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, res.Body)
+
+	return res.StatusCode, nil
+}
+
+// renderCallback executes the templates of the callback using the matched request.
+func renderCallback(c CallbackMapping, r Request) (CallbackMapping, error) {
+	if !c.Template {
+		return c, nil
+	}
+
+	data := map[string]any{"Request": r}
+	rendered := c
+
+	var err error
+	if rendered.URL, err = executeTemplate(c.URL, data); err != nil {
+		return c, err
+	}
+	if rendered.Body, err = executeTemplate(c.Body, data); err != nil {
+		return c, err
+	}
+
+	rendered.Headers = make(map[string]string, len(c.Headers))
+	for k, v := range c.Headers {
+		if rendered.Headers[k], err = executeTemplate(v, data); err != nil {
+			return c, err
+		}
+	}
+
+	return rendered, nil
+}
+
+var templateFuncs = template.FuncMap{
+	"jsonPath": templateJSONPath,
+}
+
+func parseTemplate(text string) (*template.Template, error) {
+	return template.New("").Funcs(templateFuncs).Option("missingkey=zero").Parse(text)
+}
+
+func executeTemplate(text string, data any) (string, error) {
+	tmpl, err := parseTemplate(text)
+	if err != nil {
+		return "", errors.Wrap(err, "error parsing template")
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", errors.Wrap(err, "error executing template")
+	}
+
+	return buf.String(), nil
+}
+
+// templateJSONPath returns the first value found by the expression in the JSON document.
+func templateJSONPath(document, expression string) (string, error) {
+	expr, err := jp.ParseString(expression)
+	if err != nil {
+		return "", err
+	}
+
+	parsed, err := oj.ParseString(document)
+	if err != nil {
+		return "", err
+	}
+
+	values := expr.Get(parsed)
+	if len(values) == 0 {
+		return "", nil
+	}
+
+	if s, ok := values[0].(string); ok {
+		return s, nil
+	}
+	return oj.JSON(values[0]), nil
+}
@@ -0,0 +1,167 @@
+package app
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type receivedCallback struct {
+	method string
+	path   string
+	header string
+	body   string
+}
+
+func TestCallbackDispatcher(t *testing.T) {
+	var mu sync.Mutex
+	received := make([]receivedCallback, 0)
+	var failures atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/flaky" && failures.Add(1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if r.URL.Path == "/down" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		received = append(received, receivedCallback{r.Method, r.URL.Path, r.Header.Get("X-Charge-Id"), string(body)})
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name         string
+		callback     CallbackMapping
+		wantResult   CallbackResult
+		wantReceived []receivedCallback
+	}{
+		{
+			name:         "Should fire callback",
+			callback:     CallbackMapping{Method: "POST", URL: server.URL + "/notify", Body: `{"status": "paid"}`},
+			wantResult:   CallbackResult{Method: "POST", URL: server.URL + "/notify", Body: `{"status": "paid"}`, Attempts: 1, StatusCode: 204, Success: true},
+			wantReceived: []receivedCallback{{"POST", "/notify", "", `{"status": "paid"}`}},
+		},
+		{
+			name: "Should render callback templates from the request",
+			callback: CallbackMapping{
+				Method:   "PUT",
+				URL:      server.URL + "/charges/{{jsonPath .Request.Body \"$.id\"}}",
+				Headers:  map[string]string{"X-Charge-Id": `{{index .Request.Headers "x-id"}}`},
+				Body:     `{"path": "{{.Request.Path}}"}`,
+				Template: true,
+			},
+			wantResult: CallbackResult{
+				Method: "PUT", URL: server.URL + "/charges/ch_123", Headers: map[string]string{"X-Charge-Id": "abc"},
+				Body: `{"path": "/charges"}`, Attempts: 1, StatusCode: 204, Success: true,
+			},
+			wantReceived: []receivedCallback{{"PUT", "/charges/ch_123", "abc", `{"path": "/charges"}`}},
+		},
+		{
+			name:         "Should retry failed callback",
+			callback:     CallbackMapping{Method: "POST", URL: server.URL + "/flaky", Retries: 2, RetryInterval: Duration(time.Millisecond)},
+			wantResult:   CallbackResult{Method: "POST", URL: server.URL + "/flaky", Attempts: 3, StatusCode: 204, Success: true},
+			wantReceived: []receivedCallback{{"POST", "/flaky", "", ""}},
+		},
+		{
+			name:         "Should record failure after retries",
+			callback:     CallbackMapping{Method: "POST", URL: server.URL + "/down", Retries: 1},
+			wantResult:   CallbackResult{Method: "POST", URL: server.URL + "/down", Attempts: 2, StatusCode: 500, Error: "callback returned status 500"},
+			wantReceived: []receivedCallback{},
+		},
+		{
+			name:         "Should record failure without retrying client errors",
+			callback:     CallbackMapping{Method: "POST", URL: server.URL + "/missing", Retries: 2},
+			wantResult:   CallbackResult{Method: "POST", URL: server.URL + "/missing", Attempts: 1, StatusCode: 404, Error: "callback returned status 404"},
+			wantReceived: []receivedCallback{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			received = make([]receivedCallback, 0)
+			delayer := &mockDelayer{}
+			dispatcher := NewCallbackDispatcher(delayer)
+			request := Request{ID: "req_1", Path: "/charges", Headers: map[string]string{"x-id": "abc"}, Body: `{"id": "ch_123"}`}
+
+			dispatcher.Dispatch(Mapping{FilePath: "file_1", Callbacks: []CallbackMapping{tt.callback}}, request)
+			dispatcher.Wait()
+
+			results := dispatcher.Results()
+			require.Len(t, results, 1)
+			got := results[0]
+			assert.NotEmpty(t, got.ID)
+			assert.NotEmpty(t, got.Date)
+			assert.Equal(t, "req_1", got.RequestID)
+			assert.Equal(t, "file_1", got.MappingFile)
+			got.ID, got.Date, got.RequestID, got.MappingFile = "", "", "", ""
+			assert.Equal(t, tt.wantResult, got)
+			assert.Equal(t, tt.wantReceived, received)
+
+			dispatcher.Reset()
+			assert.Empty(t, dispatcher.Results())
+		})
+	}
+}
+
+func TestCallbackMaxResults(t *testing.T) {
+	dispatcher := NewCallbackDispatcher(&mockDelayer{})
+	dispatcher.maxResults = 2
+
+	dispatcher.record(CallbackResult{ID: "1"})
+	dispatcher.record(CallbackResult{ID: "2"})
+	assert.Equal(t, []CallbackResult{{ID: "1"}, {ID: "2"}}, dispatcher.Results())
+
+	dispatcher.record(CallbackResult{ID: "3"})
+	assert.Equal(t, []CallbackResult{{ID: "2"}, {ID: "3"}}, dispatcher.Results())
+}
+
+func TestCallbackDelay(t *testing.T) {
+	delayer := &mockDelayer{}
+	dispatcher := NewCallbackDispatcher(delayer)
+	callback := CallbackMapping{Method: "GET", URL: "http://localhost:0", Delay: Delay{Fixed: FixedDelay{Duration: Duration(time.Second)}}}
+
+	dispatcher.Dispatch(Mapping{Callbacks: []CallbackMapping{callback}}, Request{})
+	dispatcher.Wait()
+
+	assert.True(t, delayer.FixedCalled)
+	assert.False(t, dispatcher.Results()[0].Success)
+}
+
+func TestValidateCallbacks(t *testing.T) {
+	mapping := Mapping{
+		Request: RequestMapping{Method: "GET", Path: CommonMatch{Exact: "/"}},
+		Callbacks: []CallbackMapping{
+			{Method: "POST", URL: "http://localhost/{{.Request.Path}}", Template: true},
+			{Retries: -1},
+			{Method: "POST", URL: "http://localhost/{{.Request.Path", Template: true},
+		},
+	}
+
+	err := mapping.Validate()
+	require.Error(t, err)
+
+	errs := err.(ValidationErrors)
+	require.Len(t, errs, 4)
+	assert.Equal(t, ValidationError{"Callbacks[1].Method", CallbackMethodMessage}, errs[0])
+	assert.Equal(t, ValidationError{"Callbacks[1].URL", CallbackURLMessage}, errs[1])
+	assert.Equal(t, ValidationError{"Callbacks[1].Retries", CallbackRetriesMessage}, errs[2])
+	assert.Equal(t, "Callbacks[2]", errs[3].Field)
+}
@@ -0,0 +1,148 @@
+package app
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/americanas-go/log"
+)
+
+const (
+	ContractViolationMessage = "Request does not match the contract of the mapping"
+	ContractStatusMessage    = "Contract status code must be a 4xx status"
+)
+
+// ContractMapping makes a mapping respond like a strict API: a request with the method and path of the mapping
+// that fails its header or body conditions gets a 4xx response listing what is wrong, instead of a 404.
+//
+// Defining it enables the mode for the mapping, unless Enabled is false, which opts the mapping out
+// when the mode is enabled globally.
+type ContractMapping struct {
+	Enabled    *bool `json:"enabled,omitempty"`
+	StatusCode int   `json:"statusCode,omitempty"`
+}
+
+func (c *ContractMapping) Validate() ValidationErrors {
+	errs := make(ValidationErrors, 0)
+	if c.StatusCode != 0 && (c.StatusCode < 400 || c.StatusCode > 499) {
+		errs = append(errs, ValidationError{"Contract.StatusCode", ContractStatusMessage})
+	}
+	return errs
+}
+
+// ContractViolationResponse is the body of the response to a request that violates the contract of a mapping.
+type ContractViolationResponse struct {
+	Message     string      `json:"message"`
+	MappingFile string      `json:"mappingFile"`
+	Errors      []FieldDiff `json:"errors"`
+}
+
+// ContractMode holds the global contract verification settings, used by mappings that don't override them.
+type ContractMode struct {
+	Enabled    bool
+	StatusCode int
+}
+
+// NewContractMode builds the global contract verification settings. A status code that isn't a 4xx status
+// falls back to 400, so a misconfiguration doesn't turn violations into successful responses.
+func NewContractMode(enabled bool, statusCode int) ContractMode {
+	if statusCode != 0 && (statusCode < 400 || statusCode > 499) {
+		log.Warnf("matcher.contract.statusCode: %s, using %d instead of %d", ContractStatusMessage, http.StatusBadRequest, statusCode)
+		statusCode = http.StatusBadRequest
+	}
+	return ContractMode{Enabled: enabled, StatusCode: statusCode}
+}
+
+// statusCode returns the status code used for contract violations of the mapping,
+// or false when the mode is not enabled for it.
+func (c ContractMode) statusCode(m *Mapping) (int, bool) {
+	enabled, status := c.Enabled, c.StatusCode
+	if m.Contract != nil {
+		enabled = m.Contract.Enabled == nil || *m.Contract.Enabled
+		if m.Contract.StatusCode != 0 {
+			status = m.Contract.StatusCode
+		}
+	}
+
+	if status == 0 {
+		status = http.StatusBadRequest
+	}
+
+	return status, enabled
+}
+
+// NewContractViolationResult builds the response to a request that reached the mapping, matching every condition
+// other than its headers and body, when contract verification is enabled for the mapping. It returns false when
+// the request should get the regular not found response.
+func NewContractViolationResult(mapping *Mapping, diff MatchDiff, mode ContractMode) (MatchResult, bool) {
+	status, enabled := mode.statusCode(mapping)
+	if !enabled {
+		return MatchResult{}, false
+	}
+
+	failed, violated := contractFailures(diff)
+	if !violated {
+		return MatchResult{}, false
+	}
+
+	result := MatchResult{
+		StatusCode: status,
+		Headers: map[string]string{
+			"Content-type":   "application/json",
+			"X-Mapping-File": mapping.FilePath,
+		},
+		Body: ContractViolationResponse{
+			Message:     ContractViolationMessage,
+			MappingFile: mapping.FilePath,
+			Errors:      failed,
+		},
+	}
+	if mapping.ID != "" {
+		result.Headers[MappingIDHeader] = mapping.ID
+	}
+
+	return result, true
+}
+
+// ContractCandidate returns the mapping, with contract verification enabled, that matches every condition of the
+// request other than its headers and body, along with its diff. When several do, the one with the highest score
+// is returned. The candidate is searched for separately from Match, whose closest mapping may have failed its path.
+func (matcher *Matcher) ContractCandidate(r Request, mappings Mappings, mode ContractMode) (Mapping, MatchDiff, bool) {
+	var best Mapping
+	var bestDiff MatchDiff
+	found := false
+
+	for _, m := range mappings[r.Method] {
+		if !matcher.toggles.Enabled(&m) {
+			continue
+		}
+		if _, enabled := mode.statusCode(&m); !enabled {
+			continue
+		}
+
+		diff := matcher.Diff(r, m)
+		if _, violated := contractFailures(diff); !violated {
+			continue
+		}
+		if !found || diff.Score > bestDiff.Score {
+			best, bestDiff, found = m, diff, true
+		}
+	}
+
+	return best, bestDiff, found
+}
+
+// contractFailures returns the failed matchers of the diff, reporting whether they are all header or body matchers.
+func contractFailures(diff MatchDiff) ([]FieldDiff, bool) {
+	failed := diff.Failed()
+	if len(failed) == 0 {
+		return failed, false
+	}
+
+	for _, f := range failed {
+		if f.Field != "body" && !strings.HasPrefix(f.Field, "headers.") {
+			return failed, false
+		}
+	}
+	return failed, true
+}
@@ -0,0 +1,182 @@
+package app
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewContractViolationResult(t *testing.T) {
+	disabled := false
+	orders := Mapping{
+		ID: "post-orders",
+		Request: RequestMapping{
+			Method:  "POST",
+			Path:    CommonMatch{Exact: "/orders"},
+			Headers: map[string]CommonMatch{"authorization": {Patterns: []string{"^Bearer .+"}}},
+			Body:    BodyMatch{CommonMatch: CommonMatch{Contains: []string{"sku"}}},
+		},
+		Response: ResponseMapping{StatusCode: 201},
+		FilePath: "post_orders.json",
+	}
+	orders.CalcMaxScoreAndCost()
+	matcher := newTestMatcher(Mappings{"POST": {orders}})
+
+	tests := []struct {
+		name       string
+		contract   *ContractMapping
+		mode       ContractMode
+		request    Request
+		wantStatus int
+		wantErrors []string
+		wantOk     bool
+	}{
+		{
+			name:    "Should not apply when contract verification is disabled",
+			request: Request{Method: "POST", Path: "/orders", Body: `{"sku": "123"}`},
+		},
+		{
+			name:       "Should apply to mappings with a contract",
+			contract:   &ContractMapping{},
+			request:    Request{Method: "POST", Path: "/orders", Body: `{"sku": "123"}`},
+			wantStatus: 400,
+			wantErrors: []string{"headers.authorization"},
+			wantOk:     true,
+		},
+		{
+			name:       "Should use the status code of the mapping",
+			contract:   &ContractMapping{StatusCode: 422},
+			mode:       ContractMode{Enabled: true, StatusCode: 400},
+			request:    Request{Method: "POST", Path: "/orders", Headers: map[string]string{"authorization": "Basic abc"}, Body: `{}`},
+			wantStatus: 422,
+			wantErrors: []string{"headers.authorization", "body"},
+			wantOk:     true,
+		},
+		{
+			name:       "Should apply to every mapping when enabled globally",
+			mode:       ContractMode{Enabled: true, StatusCode: 422},
+			request:    Request{Method: "POST", Path: "/orders", Headers: map[string]string{"authorization": "Bearer abc"}, Body: `{}`},
+			wantStatus: 422,
+			wantErrors: []string{"body"},
+			wantOk:     true,
+		},
+		{
+			name:     "Should not apply to mappings opting out",
+			contract: &ContractMapping{Enabled: &disabled},
+			mode:     ContractMode{Enabled: true},
+			request:  Request{Method: "POST", Path: "/orders", Body: `{}`},
+		},
+		{
+			name:     "Should not apply when the path does not match",
+			contract: &ContractMapping{},
+			request:  Request{Method: "POST", Path: "/orders/1", Body: `{}`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mapping := orders
+			mapping.Contract = tt.contract
+
+			result, ok := NewContractViolationResult(&mapping, matcher.Diff(tt.request, mapping), tt.mode)
+			require.Equal(t, tt.wantOk, ok)
+			if !ok {
+				return
+			}
+
+			assert.Equal(t, tt.wantStatus, result.StatusCode)
+			assert.Equal(t, map[string]string{"Content-type": "application/json", "X-Mapping-File": "post_orders.json", MappingIDHeader: "post-orders"}, result.Headers)
+
+			body, ok := result.Body.(ContractViolationResponse)
+			require.True(t, ok)
+			assert.Equal(t, ContractViolationMessage, body.Message)
+			assert.Equal(t, "post_orders.json", body.MappingFile)
+
+			fields := make([]string, 0)
+			for _, e := range body.Errors {
+				fields = append(fields, e.Field)
+			}
+			assert.Equal(t, tt.wantErrors, fields)
+		})
+	}
+}
+
+func TestContractMappingValidate(t *testing.T) {
+	assert.Empty(t, (&ContractMapping{}).Validate())
+	assert.Empty(t, (&ContractMapping{StatusCode: 422}).Validate())
+	assert.Equal(t, ValidationErrors{{"Contract.StatusCode", ContractStatusMessage}}, (&ContractMapping{StatusCode: 500}).Validate())
+}
+
+func TestServiceContractViolation(t *testing.T) {
+	mappings := getMappings()
+	for i, m := range mappings["POST"] {
+		if m.FilePath == "file_8" {
+			mappings["POST"][i].Contract = &ContractMapping{StatusCode: 422}
+		}
+	}
+	matcher := newTestMatcher(mappings)
+	journal := NewJournal()
+	service := NewService(mappings, matcher, NewScenarioHandler(matcher), &mockDelayer{}, NewResponseSelector(), NewCallbackDispatcher(&mockDelayer{}), NewStore(), journal, NewMetrics(mappings, nil), NewNoopTracing())
+
+	res := service.MatchRequest(context.Background(), Request{Method: "POST", Path: "/order", Headers: map[string]string{"authorization": "Bearer ItsMe"}, Body: `{"cart": "777"}`})
+
+	assert.False(t, res.Matched)
+	assert.Equal(t, 422, res.StatusCode)
+	body, ok := res.Body.(ContractViolationResponse)
+	require.True(t, ok)
+	assert.Equal(t, []FieldDiff{
+		{Field: "body", Matcher: ExactMatcher, Expected: `{"cart": "555"}`, Actual: `{"cart": "777"}`, Reason: `expected '{"cart": "555"}' but got '{"cart": "777"}'`},
+	}, body.Errors)
+	assert.Equal(t, 422, journal.Entries()[0].StatusCode)
+}
+
+func TestNewContractMode(t *testing.T) {
+	assert.Equal(t, ContractMode{Enabled: true}, NewContractMode(true, 0))
+	assert.Equal(t, ContractMode{Enabled: true, StatusCode: 422}, NewContractMode(true, 422))
+	assert.Equal(t, ContractMode{Enabled: true, StatusCode: 400}, NewContractMode(true, 200))
+	assert.Equal(t, ContractMode{StatusCode: 400}, NewContractMode(false, 500))
+}
+
+func TestContractCandidate(t *testing.T) {
+	orders := Mapping{
+		ID: "post-orders",
+		Request: RequestMapping{
+			Method:  "POST",
+			Path:    CommonMatch{Exact: "/orders"},
+			Headers: map[string]CommonMatch{"authorization": {Exact: "Bearer abc"}},
+		},
+		Response: ResponseMapping{StatusCode: 201},
+		FilePath: "post_orders.json",
+	}
+	items := Mapping{
+		ID: "post-order-items",
+		Request: RequestMapping{
+			Method:  "POST",
+			Path:    CommonMatch{Patterns: []string{`^/orders/[0-9]+/items$`}},
+			Headers: map[string]CommonMatch{"x-tenant": {Exact: "acme"}, "x-channel": {Exact: "web"}, "x-version": {Exact: "2"}},
+		},
+		Response: ResponseMapping{StatusCode: 201},
+		FilePath: "post_order_items.json",
+	}
+	orders.CalcMaxScoreAndCost()
+	items.CalcMaxScoreAndCost()
+
+	mappings := Mappings{"POST": {orders, items}}
+	matcher := newTestMatcher(mappings)
+	service := NewService(mappings, matcher, NewScenarioHandler(matcher), &mockDelayer{}, NewResponseSelector(), NewCallbackDispatcher(&mockDelayer{}), NewStore(), NewJournal(), NewMetrics(mappings, nil), NewNoopTracing())
+	service.contract = ContractMode{Enabled: true, StatusCode: 422}
+
+	r := Request{Method: "POST", Path: "/orders", Headers: map[string]string{"x-tenant": "acme", "x-channel": "web", "x-version": "2"}}
+
+	closest, _, partial := matcher.Match(r, mappings, nil)
+	require.True(t, partial)
+	require.Equal(t, "post_order_items.json", closest.FilePath, "the closest mapping should be the one failing its path")
+
+	result := service.MatchRequest(context.Background(), r)
+	assert.Equal(t, 422, result.StatusCode)
+	body, ok := result.Body.(ContractViolationResponse)
+	require.True(t, ok)
+	assert.Equal(t, "post_orders.json", body.MappingFile)
+}
@@ -37,6 +37,10 @@ func (ResponseDelayer) Apply(delay *Delay) {
 type Duration time.Duration
 
 func (d *Duration) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+
 	var s string
 	err := oj.Unmarshal(data, &s)
 	if err != nil {
@@ -51,3 +55,11 @@ func (d *Duration) UnmarshalJSON(data []byte) error {
 	*d = Duration(dur)
 	return nil
 }
+
+// MarshalJSON writes the duration in the same format it is read from, a zero duration is written as null.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	if d == 0 {
+		return []byte("null"), nil
+	}
+	return oj.Marshal(time.Duration(d).String())
+}
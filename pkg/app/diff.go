@@ -0,0 +1,282 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	DefaultNearMissCandidates = 3
+
+	ExactMatcher    = "exact"
+	ContainsMatcher = "contains"
+	PatternMatcher  = "pattern"
+	JsonPathMatcher = "jsonPath"
+	SchemaMatcher   = "schema"
+	StateMatcher    = "state"
+)
+
+// FieldDiff is the outcome of a single matcher of a mapping against a request.
+type FieldDiff struct {
+	Field    string `json:"field"`
+	Matcher  string `json:"matcher"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+	Matched  bool   `json:"matched"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// MatchDiff explains which matchers of a mapping passed and which failed for a request.
+type MatchDiff struct {
+	MappingFile string          `json:"mappingFile"`
+	Score       int             `json:"score"`
+	MaxScore    int             `json:"maxScore"`
+	Mapping     *RequestMapping `json:"mapping"`
+	Fields      []FieldDiff     `json:"fields"`
+}
+
+// Failed returns only the matchers that did not match.
+func (d MatchDiff) Failed() []FieldDiff {
+	failed := make([]FieldDiff, 0)
+	for _, f := range d.Fields {
+		if !f.Matched {
+			failed = append(failed, f)
+		}
+	}
+	return failed
+}
+
+func (d MatchDiff) methodMatched() bool {
+	for _, f := range d.Fields {
+		if f.Field == "method" {
+			return f.Matched
+		}
+	}
+	return true
+}
+
+func (d MatchDiff) String() string {
+	reasons := make([]string, 0)
+	for _, f := range d.Failed() {
+		reasons = append(reasons, fmt.Sprintf("%s (%s): %s", f.Field, f.Matcher, f.Reason))
+	}
+	return fmt.Sprintf("%s [%d/%d] %s", d.MappingFile, d.Score, d.MaxScore, strings.Join(reasons, "; "))
+}
+
+// Diff runs every matcher of the mapping against the request, recording the result of each one.
+func (matcher *Matcher) Diff(r Request, m Mapping) MatchDiff {
+	req := m.Request
+	diff := MatchDiff{
+		MappingFile: m.FilePath,
+		MaxScore:    m.MaxScore,
+		Mapping:     &req,
+		Fields:      make([]FieldDiff, 0),
+	}
+
+	diff.add(exactField("method", m.Request.Method, r.Method, r.Method == m.Request.Method), false)
+
+	if m.Request.Scheme != "" {
+		diff.add(exactField("scheme", m.Request.Scheme, r.Scheme, matcher.matchScheme(r, m)), true)
+	}
+	if m.Request.Port != "" {
+		diff.add(exactField("port", m.Request.Port, r.Port, matcher.matchPort(r, m)), true)
+	}
+
+	matcher.diffCommon(&diff, "host", m.Request.Host, r.Host)
+	matcher.diffCommon(&diff, "clientCertificate", m.Request.ClientCertificate, r.ClientCertificate)
+	matcher.diffCommon(&diff, "path", m.Request.Path, r.Path)
+
+	headerKeys := make([]string, 0, len(m.Request.Headers))
+	for k := range m.Request.Headers {
+		headerKeys = append(headerKeys, k)
+	}
+	sort.Strings(headerKeys)
+
+	for _, k := range headerKeys {
+		field := "headers." + strings.ToLower(k)
+		mVal := m.Request.Headers[k]
+		rVal, ok := r.Headers[strings.ToLower(k)]
+		if !ok {
+			for _, f := range headerFields(field, mVal) {
+				f.Reason = "header is not present in the request"
+				diff.add(f, headerScored(mVal, f))
+			}
+			continue
+		}
+		for _, f := range headerFields(field, mVal) {
+			diff.add(matcher.diffField(f, rVal), headerScored(mVal, f))
+		}
+	}
+
+	matcher.diffCommon(&diff, "body", m.Request.Body.CommonMatch, r.Body)
+	for _, expr := range m.Request.Body.JsonPath {
+		f := FieldDiff{Field: "body", Matcher: JsonPathMatcher, Expected: expr, Actual: r.Body}
+		f.Matched = matcher.jsonPathCache.Match([]string{expr}, r.Body)
+		if !f.Matched {
+			f.Reason = "expression returned no results"
+		}
+		diff.add(f, true)
+	}
+
+	if body := m.Request.Body; body.HasSchema() && body.Exact == "" {
+		f := FieldDiff{Field: "body", Matcher: SchemaMatcher, Expected: schemaSource(body), Actual: r.Body}
+		violations := SchemaViolations(body, r.Body)
+		f.Matched = len(violations) == 0
+		if !f.Matched {
+			f.Reason = strings.Join(violations, "; ")
+		}
+		diff.add(f, true)
+	}
+
+	return diff
+}
+
+// schemaSource describes the schema of the body match, by its file or its inline definition.
+func schemaSource(b BodyMatch) string {
+	if b.SchemaFile != "" {
+		return b.SchemaFile
+	}
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, b.Schema); err != nil {
+		return string(b.Schema)
+	}
+	return buf.String()
+}
+
+// Closest returns up to n mappings, of any method, that came closer to matching the request,
+// ranked by how many of their matchers passed. Scenario mappings are also checked against the current
+// state of their scenario in scenarioStates.
+func (matcher *Matcher) Closest(r Request, mappings Mappings, scenarioStates map[string]string, n int) []MatchDiff {
+	diffs := make([]MatchDiff, 0)
+	for _, methodMappings := range mappings {
+		for _, m := range methodMappings {
+			if !matcher.toggles.Enabled(&m) {
+				continue
+			}
+			d := matcher.Diff(r, m)
+			if d.Score == 0 {
+				continue
+			}
+			if m.Scenario != nil && scenarioStates != nil {
+				d.add(scenarioField(m.Scenario, scenarioStates[m.Scenario.Name]), false)
+			}
+			diffs = append(diffs, d)
+		}
+	}
+
+	SortDiffs(diffs)
+
+	if n > 0 && len(diffs) > n {
+		diffs = diffs[:n]
+	}
+
+	return diffs
+}
+
+// SortDiffs orders diffs by method match, score and number of failed matchers.
+func SortDiffs(diffs []MatchDiff) {
+	sort.SliceStable(diffs, func(i, j int) bool {
+		mi, mj := diffs[i].methodMatched(), diffs[j].methodMatched()
+		if mi != mj {
+			return mi
+		}
+		if diffs[i].Score != diffs[j].Score {
+			return diffs[i].Score > diffs[j].Score
+		}
+		if fi, fj := len(diffs[i].Failed()), len(diffs[j].Failed()); fi != fj {
+			return fi < fj
+		}
+		return diffs[i].MappingFile < diffs[j].MappingFile
+	})
+}
+
+func (d *MatchDiff) add(f FieldDiff, scored bool) {
+	if f.Matched && scored {
+		d.Score++
+	}
+	d.Fields = append(d.Fields, f)
+}
+
+func exactField(field, expected, actual string, matched bool) FieldDiff {
+	f := FieldDiff{Field: field, Matcher: ExactMatcher, Expected: expected, Actual: actual, Matched: matched}
+	if !matched {
+		f.Reason = fmt.Sprintf("expected '%s' but got '%s'", expected, actual)
+	}
+	return f
+}
+
+// commonFields lists the matchers of a CommonMatch, when exact is defined it is the only one considered.
+func commonFields(field string, c CommonMatch) []FieldDiff {
+	fields := make([]FieldDiff, 0)
+	if c.Exact != "" {
+		return append(fields, FieldDiff{Field: field, Matcher: ExactMatcher, Expected: c.Exact})
+	}
+	for _, v := range c.Contains {
+		fields = append(fields, FieldDiff{Field: field, Matcher: ContainsMatcher, Expected: v})
+	}
+	for _, p := range c.Patterns {
+		fields = append(fields, FieldDiff{Field: field, Matcher: PatternMatcher, Expected: p})
+	}
+	return fields
+}
+
+// headerFields lists every matcher of a header, which unlike other fields checks contains and patterns
+// along with exact.
+func headerFields(field string, c CommonMatch) []FieldDiff {
+	fields := make([]FieldDiff, 0)
+	if c.Exact != "" {
+		fields = append(fields, FieldDiff{Field: field, Matcher: ExactMatcher, Expected: c.Exact})
+	}
+	return append(fields, commonFields(field, CommonMatch{Contains: c.Contains, Patterns: c.Patterns})...)
+}
+
+// headerScored tells whether the matcher counts towards the score, only exact does when it is defined.
+func headerScored(c CommonMatch, f FieldDiff) bool {
+	return c.Exact == "" || f.Matcher == ExactMatcher
+}
+
+// scenarioField records whether the scenario of the mapping is in the state the mapping expects.
+func scenarioField(scenario *ScenarioMapping, current string) FieldDiff {
+	f := FieldDiff{Field: "scenario", Matcher: StateMatcher, Expected: scenario.State, Actual: current, Matched: scenario.State == current}
+	if !f.Matched {
+		f.Reason = fmt.Sprintf("scenario '%s' is in the state '%s'", scenario.Name, current)
+	}
+	return f
+}
+
+func (matcher *Matcher) diffCommon(diff *MatchDiff, field string, c CommonMatch, value string) {
+	for _, f := range commonFields(field, c) {
+		diff.add(matcher.diffField(f, value), true)
+	}
+}
+
+func (matcher *Matcher) diffField(f FieldDiff, value string) FieldDiff {
+	f.Actual = value
+	// hosts are compared ignoring case, like matchHost does
+	host := f.Field == "host"
+	switch f.Matcher {
+	case ExactMatcher:
+		f.Matched = value == f.Expected || (host && strings.EqualFold(value, f.Expected))
+		if !f.Matched {
+			f.Reason = fmt.Sprintf("expected '%s' but got '%s'", f.Expected, value)
+		}
+	case ContainsMatcher:
+		f.Matched = strings.Contains(value, f.Expected) || (host && strings.Contains(strings.ToLower(value), strings.ToLower(f.Expected)))
+		if !f.Matched {
+			f.Reason = fmt.Sprintf("value does not contain '%s'", f.Expected)
+		}
+	case PatternMatcher:
+		pattern := f.Expected
+		if host {
+			pattern = hostPattern(pattern)
+		}
+		f.Matched = matcher.regexCache.Match(pattern, value)
+		if !f.Matched {
+			f.Reason = fmt.Sprintf("value does not match pattern '%s'", f.Expected)
+		}
+	}
+	return f
+}
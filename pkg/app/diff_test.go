@@ -0,0 +1,185 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestMatcher(mappings Mappings) *Matcher {
+	regexCache := NewRegexCache()
+	jsonPathCache := NewJSONPathCache()
+	for _, method := range mappings {
+		for _, mapping := range method {
+			_ = regexCache.AddFromMapping(mapping)
+			_ = jsonPathCache.AddExpressions(mapping.Request.Body.JsonPath)
+		}
+	}
+	return NewMatcher(regexCache, jsonPathCache)
+}
+
+func TestDiff(t *testing.T) {
+	mapping := Mapping{
+		Request: RequestMapping{
+			Method:  "POST",
+			Path:    CommonMatch{Contains: []string{"bears", "contains"}},
+			Headers: map[string]CommonMatch{"content-type": {Exact: "application/json"}, "authorization": {Patterns: []string{"^Bearer .+$"}}},
+			Body:    BodyMatch{JsonPath: []string{"$.name", "$.honey"}},
+		},
+		MaxScore: 6,
+		FilePath: "file_1",
+	}
+	matcher := newTestMatcher(Mappings{"POST": {mapping}})
+
+	tests := []struct {
+		name       string
+		input      Request
+		wantScore  int
+		wantFailed []FieldDiff
+	}{
+		{
+			name:       "Should report no failures when request matches",
+			input:      Request{Method: "POST", Path: "/bears/contains", Headers: map[string]string{"content-type": "application/json", "authorization": "Bearer 🐻"}, Body: `{"name": "Mr Bear", "honey": true}`},
+			wantScore:  6,
+			wantFailed: []FieldDiff{},
+		},
+		{
+			name:      "Should report each failed matcher",
+			input:     Request{Method: "PUT", Path: "/bears/123", Headers: map[string]string{"content-type": "text/plain"}, Body: `{"name": "Mr Bear"}`},
+			wantScore: 2,
+			wantFailed: []FieldDiff{
+				{Field: "method", Matcher: ExactMatcher, Expected: "POST", Actual: "PUT", Reason: "expected 'POST' but got 'PUT'"},
+				{Field: "path", Matcher: ContainsMatcher, Expected: "contains", Actual: "/bears/123", Reason: "value does not contain 'contains'"},
+				{Field: "headers.authorization", Matcher: PatternMatcher, Expected: "^Bearer .+$", Reason: "header is not present in the request"},
+				{Field: "headers.content-type", Matcher: ExactMatcher, Expected: "application/json", Actual: "text/plain", Reason: "expected 'application/json' but got 'text/plain'"},
+				{Field: "body", Matcher: JsonPathMatcher, Expected: "$.honey", Actual: `{"name": "Mr Bear"}`, Reason: "expression returned no results"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := matcher.Diff(tt.input, mapping)
+			assert.Equal(t, "file_1", diff.MappingFile)
+			assert.Equal(t, 6, diff.MaxScore)
+			assert.Equal(t, tt.wantScore, diff.Score)
+			assert.Equal(t, tt.wantFailed, diff.Failed())
+		})
+	}
+}
+
+func TestDiffHeaderMatchers(t *testing.T) {
+	mapping := Mapping{
+		Request: RequestMapping{
+			Method:  "GET",
+			Path:    CommonMatch{Exact: "/bears"},
+			Headers: map[string]CommonMatch{"accept": {Exact: "application/json", Contains: []string{"xml"}, Patterns: []string{"^application/"}}},
+		},
+		MaxScore: 2,
+		FilePath: "file_1",
+	}
+	matcher := newTestMatcher(Mappings{"GET": {mapping}})
+
+	diff := matcher.Diff(Request{Method: "GET", Path: "/bears", Headers: map[string]string{"accept": "application/json"}}, mapping)
+	assert.Equal(t, 2, diff.Score)
+	assert.Equal(t, []FieldDiff{
+		{Field: "headers.accept", Matcher: ContainsMatcher, Expected: "xml", Actual: "application/json", Reason: "value does not contain 'xml'"},
+	}, diff.Failed())
+
+	diff = matcher.Diff(Request{Method: "GET", Path: "/bears"}, mapping)
+	assert.Equal(t, 1, diff.Score)
+	assert.Len(t, diff.Failed(), 3)
+}
+
+func TestClosestScenarioState(t *testing.T) {
+	matcher := newTestMatcher(Mappings{})
+	handler := NewScenarioHandler(matcher)
+	for _, m := range []Mapping{
+		{
+			Scenario: &ScenarioMapping{Name: "cart", StartingState: true, State: "empty", NewState: "full"},
+			Request:  RequestMapping{Method: "POST", Path: CommonMatch{Exact: "/cart"}},
+			MaxScore: 1,
+			FilePath: "cart_empty.json",
+		},
+		{
+			Scenario: &ScenarioMapping{Name: "cart", State: "full"},
+			Request:  RequestMapping{Method: "GET", Path: CommonMatch{Exact: "/cart"}},
+			MaxScore: 1,
+			FilePath: "cart_full.json",
+		},
+	} {
+		handler.AddScenario(m)
+	}
+
+	r := Request{Method: "GET", Path: "/cart"}
+	diffs := matcher.Closest(r, handler.scenarioMappings, handler.CurrentStates(r), 3)
+	require.Len(t, diffs, 2)
+	assert.Equal(t, "cart_full.json", diffs[0].MappingFile)
+	assert.Equal(t, 1, diffs[0].Score)
+	assert.Equal(t, []FieldDiff{
+		{Field: "scenario", Matcher: StateMatcher, Expected: "full", Actual: "empty", Reason: "scenario 'cart' is in the state 'empty'"},
+	}, diffs[0].Failed())
+}
+
+func TestClosest(t *testing.T) {
+	mappings := getMappings()
+	matcher := newTestMatcher(mappings)
+
+	tests := []struct {
+		name      string
+		input     Request
+		n         int
+		wantFiles []string
+	}{
+		{
+			name:      "Should rank candidates by score",
+			input:     Request{Method: "POST", Path: "/order", Headers: map[string]string{"authorization": "Bearer NotMe"}, Body: `{"cart": "555"}`},
+			n:         3,
+			wantFiles: []string{"file_9", "file_8"},
+		},
+		{
+			name:      "Should limit the number of candidates",
+			input:     Request{Method: "GET", Path: "/health"},
+			n:         1,
+			wantFiles: []string{"file_15"},
+		},
+		{
+			name:      "Should include candidates from other methods",
+			input:     Request{Method: "PUT", Path: "/cart/123"},
+			n:         3,
+			wantFiles: []string{"file_14", "file_6"},
+		},
+		{
+			name:      "Should return no candidates when nothing matches",
+			input:     Request{Method: "GET", Path: "/nothing"},
+			n:         3,
+			wantFiles: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := matcher.Closest(tt.input, mappings, nil, tt.n)
+			files := make([]string, 0)
+			for _, d := range got {
+				files = append(files, d.MappingFile)
+			}
+			require.Equal(t, tt.wantFiles, files)
+		})
+	}
+}
+
+func TestMatchDiffString(t *testing.T) {
+	diff := MatchDiff{
+		MappingFile: "file_1",
+		Score:       1,
+		MaxScore:    2,
+		Fields: []FieldDiff{
+			{Field: "path", Matcher: ExactMatcher, Matched: true},
+			{Field: "headers.accept", Matcher: ContainsMatcher, Reason: "value does not contain 'json'"},
+		},
+	}
+
+	assert.Equal(t, "file_1 [1/2] headers.accept (contains): value does not contain 'json'", diff.String())
+}
@@ -0,0 +1,39 @@
+package app
+
+// Explanation describes how a request is matched against the loaded mappings.
+type Explanation struct {
+	Request     Request             `json:"request"`
+	Matched     bool                `json:"matched"`
+	MappingID   string              `json:"mappingId,omitempty"`
+	MappingName string              `json:"mappingName,omitempty"`
+	Match       *MatchDiff          `json:"match,omitempty"`
+	Scenario    *ScenarioTransition `json:"scenario,omitempty"`
+	Candidates  []MatchDiff         `json:"candidates"`
+}
+
+// Explain matches the request the same way Service.MatchRequest does and explains the result, listing
+// up to n of the closest mappings. Scenarios are not moved to their new state and no response is produced.
+func Explain(matcher *Matcher, scenarioHandler *ScenarioHandler, mappings Mappings, r Request, n int) Explanation {
+	mapping, matched, _ := scenarioHandler.PeekScenario(r)
+	if !matched {
+		mapping, matched, _ = matcher.Match(r, mappings, nil)
+	}
+
+	explanation := Explanation{
+		Request:    r,
+		Matched:    matched,
+		Candidates: nearMisses(matcher, scenarioHandler, mappings, r, n),
+	}
+
+	if matched {
+		diff := matcher.Diff(r, mapping)
+		explanation.Match = &diff
+		explanation.MappingID = mapping.ID
+		explanation.MappingName = mapping.Name
+		if mapping.Scenario != nil {
+			explanation.Scenario = &ScenarioTransition{Name: mapping.Scenario.Name, From: mapping.Scenario.State, To: mapping.Scenario.NewState}
+		}
+	}
+
+	return explanation
+}
@@ -0,0 +1,75 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExplain(t *testing.T) {
+	matcher := NewMatcher(NewRegexCache(), NewJSONPathCache())
+	scenarioHandler := NewScenarioHandler(matcher)
+	scenarioHandler.AddScenario(Mapping{
+		Scenario: &ScenarioMapping{Name: "cart", StartingState: true, State: "empty", NewState: "full"},
+		Request:  RequestMapping{Method: "POST", Path: CommonMatch{Exact: "/cart"}},
+		Response: ResponseMapping{StatusCode: 201},
+		MaxScore: 1,
+		FilePath: "cart_empty.json",
+	})
+	scenarioHandler.AddScenario(Mapping{
+		Scenario: &ScenarioMapping{Name: "cart", State: "full"},
+		Request:  RequestMapping{Method: "POST", Path: CommonMatch{Exact: "/cart"}},
+		Response: ResponseMapping{StatusCode: 200},
+		MaxScore: 1,
+		FilePath: "cart_full.json",
+	})
+	mappings := Mappings{
+		"GET": []Mapping{
+			{
+				ID:       "get-order",
+				Name:     "Order",
+				Request:  RequestMapping{Method: "GET", Path: CommonMatch{Exact: "/order"}, Headers: map[string]CommonMatch{"accept": {Exact: "application/json"}}},
+				MaxScore: 2,
+				FilePath: "get_order.json",
+			},
+		},
+	}
+
+	t.Run("Should explain a matched request", func(t *testing.T) {
+		r := Request{Method: "GET", Path: "/order", Headers: map[string]string{"accept": "application/json"}}
+
+		e := Explain(matcher, scenarioHandler, mappings, r, DefaultNearMissCandidates)
+
+		assert.True(t, e.Matched)
+		assert.Equal(t, "get-order", e.MappingID)
+		assert.Equal(t, "Order", e.MappingName)
+		assert.Equal(t, "get_order.json", e.Match.MappingFile)
+		assert.Equal(t, 2, e.Match.Score)
+		assert.Equal(t, 2, e.Match.MaxScore)
+	})
+
+	t.Run("Should explain an unmatched request with its candidates", func(t *testing.T) {
+		r := Request{Method: "GET", Path: "/order", Headers: map[string]string{}}
+
+		e := Explain(matcher, scenarioHandler, mappings, r, DefaultNearMissCandidates)
+
+		assert.False(t, e.Matched)
+		assert.Nil(t, e.Match)
+		assert.Len(t, e.Candidates, 1)
+		assert.Equal(t, "get_order.json", e.Candidates[0].MappingFile)
+		assert.Equal(t, 1, e.Candidates[0].Score)
+		assert.Equal(t, "header is not present in the request", e.Candidates[0].Failed()[0].Reason)
+	})
+
+	t.Run("Should explain a scenario match without changing its state", func(t *testing.T) {
+		r := Request{Method: "POST", Path: "/cart"}
+
+		for i := 0; i < 2; i++ {
+			e := Explain(matcher, scenarioHandler, mappings, r, DefaultNearMissCandidates)
+
+			assert.True(t, e.Matched)
+			assert.Equal(t, "cart_empty.json", e.Match.MappingFile)
+			assert.Equal(t, &ScenarioTransition{Name: "cart", From: "empty", To: "full"}, e.Scenario)
+		}
+	})
+}
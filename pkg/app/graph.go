@@ -0,0 +1,385 @@
+package app
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	GraphFormatJSON    = "json"
+	GraphFormatDOT     = "dot"
+	GraphFormatMermaid = "mermaid"
+
+	GraphFormatMessage = "unknown graph format '%s', use 'json', 'dot' or 'mermaid'"
+
+	// AnyState is the source of the transitions that move a scenario from any of its states.
+	AnyState = "*"
+)
+
+// ScenarioGraph is the flow of a scenario: its states and the transitions between them.
+type ScenarioGraph struct {
+	Name          string                    `json:"name"`
+	StartingState string                    `json:"startingState"`
+	CurrentState  string                    `json:"currentState"`
+	States        []ScenarioGraphState      `json:"states"`
+	Transitions   []ScenarioGraphTransition `json:"transitions"`
+}
+
+// ScenarioGraphState is a state of the scenario. Unreachable states can't be reached from the starting state,
+// and dead ends are states the scenario never leaves.
+type ScenarioGraphState struct {
+	Name        string `json:"name"`
+	Starting    bool   `json:"starting,omitempty"`
+	Current     bool   `json:"current,omitempty"`
+	Unreachable bool   `json:"unreachable,omitempty"`
+	DeadEnd     bool   `json:"deadEnd,omitempty"`
+}
+
+// ScenarioGraphTransition moves the scenario from a state, or from any state, to another, when the mapping
+// is matched or when the state expires.
+type ScenarioGraphTransition struct {
+	From        string `json:"from"`
+	To          string `json:"to"`
+	Label       string `json:"label"`
+	MappingFile string `json:"mappingFile,omitempty"`
+}
+
+// Graphs returns the graph of every scenario, sorted by name.
+func (hand *ScenarioHandler) Graphs() []ScenarioGraph {
+	hand.mu.Lock()
+	defer hand.mu.Unlock()
+
+	graphs := make([]ScenarioGraph, 0, len(hand.scenarios))
+	for name, sc := range hand.scenarios {
+		graphs = append(graphs, hand.graph(name, sc))
+	}
+	sort.Slice(graphs, func(i, j int) bool {
+		return graphs[i].Name < graphs[j].Name
+	})
+
+	return graphs
+}
+
+// Graph returns the graph of the scenario, or false if there is no such scenario.
+func (hand *ScenarioHandler) Graph(name string) (ScenarioGraph, bool) {
+	hand.mu.Lock()
+	defer hand.mu.Unlock()
+
+	sc, ok := hand.scenarios[name]
+	if !ok {
+		return ScenarioGraph{}, false
+	}
+	return hand.graph(name, sc), true
+}
+
+func (hand *ScenarioHandler) graph(name string, sc ScenarioState) ScenarioGraph {
+	g := ScenarioGraph{
+		Name:          name,
+		StartingState: sc.startingState(),
+		CurrentState:  sc.CurrentState,
+		States:        make([]ScenarioGraphState, 0, len(sc.States)),
+		Transitions:   make([]ScenarioGraphTransition, 0),
+	}
+
+	seen := make(map[ScenarioGraphTransition]bool)
+	add := func(t ScenarioGraphTransition) {
+		if t.From != t.To && !seen[t] {
+			seen[t] = true
+			g.Transitions = append(g.Transitions, t)
+		}
+	}
+
+	for state, mappings := range sc.States {
+		for _, m := range mappings {
+			if m.Scenario.NewState != "" {
+				add(ScenarioGraphTransition{From: state, To: m.Scenario.NewState, Label: requestLabel(m.Request), MappingFile: m.FilePath})
+			}
+		}
+
+		if expiry := sc.expiry(state); expiry != nil {
+			to := expiry.ResetTo
+			if to == "" {
+				to = g.StartingState
+			}
+			add(ScenarioGraphTransition{From: state, To: to, Label: expiry.label()})
+		}
+	}
+
+	for _, m := range hand.triggers {
+		for _, t := range m.Transitions {
+			if t.Scenario != name {
+				continue
+			}
+			from := t.State
+			if from == "" {
+				from = AnyState
+			}
+			add(ScenarioGraphTransition{From: from, To: t.NewState, Label: requestLabel(m.Request), MappingFile: m.FilePath})
+		}
+	}
+
+	sort.Slice(g.Transitions, func(i, j int) bool {
+		a, b := g.Transitions[i], g.Transitions[j]
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		if a.To != b.To {
+			return a.To < b.To
+		}
+		return a.Label < b.Label
+	})
+
+	reachable := g.reachable()
+	leaves := make(map[string]bool)
+	anyTargets := make(map[string]int)
+	for _, t := range g.Transitions {
+		if t.From == AnyState {
+			anyTargets[t.To]++
+			continue
+		}
+		leaves[t.From] = true
+	}
+
+	for state := range sc.States {
+		// a transition from any state leaves every state other than its target
+		leavesFromAny := len(anyTargets) > 1 || (len(anyTargets) == 1 && anyTargets[state] == 0)
+		g.States = append(g.States, ScenarioGraphState{
+			Name:        state,
+			Starting:    state == g.StartingState,
+			Current:     state == g.CurrentState,
+			Unreachable: !reachable[state],
+			DeadEnd:     !leaves[state] && !leavesFromAny,
+		})
+	}
+	sort.Slice(g.States, func(i, j int) bool {
+		if g.States[i].Starting != g.States[j].Starting {
+			return g.States[i].Starting
+		}
+		return g.States[i].Name < g.States[j].Name
+	})
+
+	return g
+}
+
+// reachable returns the states reached from the starting state, following the transitions.
+func (g ScenarioGraph) reachable() map[string]bool {
+	reached := make(map[string]bool)
+	if g.StartingState == "" {
+		return reached
+	}
+
+	pending := []string{g.StartingState}
+	for _, t := range g.Transitions {
+		if t.From == AnyState {
+			pending = append(pending, t.To)
+		}
+	}
+
+	for len(pending) > 0 {
+		state := pending[0]
+		pending = pending[1:]
+		if reached[state] {
+			continue
+		}
+		reached[state] = true
+
+		for _, t := range g.Transitions {
+			if t.From == state && !reached[t.To] {
+				pending = append(pending, t.To)
+			}
+		}
+	}
+
+	return reached
+}
+
+// UnreachableStates returns the names of the states that can't be reached from the starting state.
+func (g ScenarioGraph) UnreachableStates() []string {
+	names := make([]string, 0)
+	for _, s := range g.States {
+		if s.Unreachable {
+			names = append(names, s.Name)
+		}
+	}
+	return names
+}
+
+// DeadEnds returns the names of the states the scenario never leaves.
+func (g ScenarioGraph) DeadEnds() []string {
+	names := make([]string, 0)
+	for _, s := range g.States {
+		if s.DeadEnd {
+			names = append(names, s.Name)
+		}
+	}
+	return names
+}
+
+// DOT writes the graph in the Graphviz DOT language. The starting state has a double border, the current state
+// is filled, unreachable states are dashed and dead ends are red.
+func (g ScenarioGraph) DOT() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph %s {\n", dotQuote(g.Name))
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box, style=rounded];\n")
+
+	for _, s := range g.States {
+		attrs := make([]string, 0)
+		style := "rounded"
+		if s.Starting {
+			attrs = append(attrs, "peripheries=2")
+		}
+		if s.Current {
+			style += ",filled"
+			attrs = append(attrs, "fillcolor=lightblue")
+		}
+		if s.Unreachable {
+			style += ",dashed"
+			attrs = append(attrs, "fontcolor=gray")
+		}
+		if s.DeadEnd {
+			attrs = append(attrs, "color=red")
+		}
+		attrs = append(attrs, "style="+dotQuote(style))
+		fmt.Fprintf(&b, "  %s [%s];\n", dotQuote(s.Name), strings.Join(attrs, ", "))
+	}
+
+	if g.hasAnyState() {
+		fmt.Fprintf(&b, "  %s [shape=plaintext, label=\"any state\"];\n", dotQuote(AnyState))
+	}
+
+	if g.StartingState != "" {
+		b.WriteString("  \"\" [shape=point];\n")
+		fmt.Fprintf(&b, "  \"\" -> %s;\n", dotQuote(g.StartingState))
+	}
+
+	for _, t := range g.Transitions {
+		fmt.Fprintf(&b, "  %s -> %s [label=%s];\n", dotQuote(t.From), dotQuote(t.To), dotQuote(t.Label))
+	}
+
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// Mermaid writes the graph as a Mermaid state diagram, with the same markings used by DOT.
+func (g ScenarioGraph) Mermaid() string {
+	ids := make(map[string]string, len(g.States))
+	for i, s := range g.States {
+		ids[s.Name] = fmt.Sprintf("s%d", i)
+	}
+	ids[AnyState] = "any"
+
+	var b strings.Builder
+	b.WriteString("stateDiagram-v2\n")
+	fmt.Fprintf(&b, "  %%%% %s\n", g.Name)
+
+	for _, s := range g.States {
+		fmt.Fprintf(&b, "  state %s as %s\n", mermaidQuote(s.Name), ids[s.Name])
+	}
+	if g.hasAnyState() {
+		fmt.Fprintf(&b, "  state %s as %s\n", mermaidQuote("any state"), ids[AnyState])
+	}
+
+	if g.StartingState != "" {
+		fmt.Fprintf(&b, "  [*] --> %s\n", ids[g.StartingState])
+	}
+
+	for _, t := range g.Transitions {
+		fmt.Fprintf(&b, "  %s --> %s : %s\n", ids[t.From], ids[t.To], mermaidEscape(t.Label))
+	}
+
+	classes := []struct {
+		name  string
+		style string
+		has   func(ScenarioGraphState) bool
+	}{
+		{"current", "fill:#add8e6", func(s ScenarioGraphState) bool { return s.Current }},
+		{"unreachable", "color:#999,stroke-dasharray:5 5", func(s ScenarioGraphState) bool { return s.Unreachable }},
+		{"deadEnd", "stroke:#f00", func(s ScenarioGraphState) bool { return s.DeadEnd }},
+	}
+	for _, c := range classes {
+		for _, s := range g.States {
+			if c.has(s) {
+				fmt.Fprintf(&b, "  classDef %s %s\n", c.name, c.style)
+				break
+			}
+		}
+		for _, s := range g.States {
+			if c.has(s) {
+				fmt.Fprintf(&b, "  class %s %s\n", ids[s.Name], c.name)
+			}
+		}
+	}
+
+	return b.String()
+}
+
+func (g ScenarioGraph) hasAnyState() bool {
+	for _, t := range g.Transitions {
+		if t.From == AnyState {
+			return true
+		}
+	}
+	return false
+}
+
+// FormatScenarioGraphs writes the graphs in the DOT or Mermaid format, one after the other.
+func FormatScenarioGraphs(graphs []ScenarioGraph, format string) (string, error) {
+	var write func(ScenarioGraph) string
+	switch format {
+	case GraphFormatDOT:
+		write = ScenarioGraph.DOT
+	case GraphFormatMermaid:
+		write = ScenarioGraph.Mermaid
+	default:
+		return "", fmt.Errorf(GraphFormatMessage, format)
+	}
+
+	var b strings.Builder
+	for i, g := range graphs {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(write(g))
+	}
+	return b.String(), nil
+}
+
+// label describes the expiry for the transition it causes.
+func (e *ScenarioExpiry) label() string {
+	conditions := make([]string, 0, 2)
+	if e.Inactivity > 0 {
+		conditions = append(conditions, fmt.Sprintf("%s inactive", time.Duration(e.Inactivity)))
+	}
+	if e.Requests > 0 {
+		conditions = append(conditions, fmt.Sprintf("%d requests", e.Requests))
+	}
+	return "expiry: " + strings.Join(conditions, " or ")
+}
+
+// requestLabel describes the request matched by a mapping, by its method and path.
+func requestLabel(r RequestMapping) string {
+	path := r.Path.Exact
+	switch {
+	case path != "":
+	case len(r.Path.Patterns) > 0:
+		path = strings.Join(r.Path.Patterns, " ")
+	case len(r.Path.Contains) > 0:
+		path = "*" + strings.Join(r.Path.Contains, "*") + "*"
+	}
+	return strings.TrimSpace(r.Method + " " + path)
+}
+
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
+
+func mermaidQuote(s string) string {
+	return `"` + mermaidEscape(s) + `"`
+}
+
+func mermaidEscape(s string) string {
+	return strings.NewReplacer(`"`, "#quot;", ";", "#59;", "\n", " ").Replace(s)
+}
@@ -0,0 +1,144 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newSessionScenario(t *testing.T) *ScenarioHandler {
+	t.Helper()
+	handler := NewScenarioHandler(NewMatcher(NewRegexCache(), NewJSONPathCache()))
+	for _, m := range []Mapping{
+		{
+			Scenario: &ScenarioMapping{Name: "session", StartingState: true, State: "Logged Out", NewState: "Logged In"},
+			Request:  RequestMapping{Method: "POST", Path: CommonMatch{Exact: "/login"}},
+			Response: ResponseMapping{StatusCode: 200},
+			MaxScore: 1,
+			FilePath: "login.json",
+		}, {
+			Scenario: &ScenarioMapping{Name: "session", State: "Logged In", StateExpiry: &ScenarioExpiry{Inactivity: Duration(30 * time.Minute)}},
+			Request:  RequestMapping{Method: "GET", Path: CommonMatch{Patterns: []string{"/me/.*"}}},
+			Response: ResponseMapping{StatusCode: 200},
+			MaxScore: 1,
+			FilePath: "me.json",
+		}, {
+			Scenario: &ScenarioMapping{Name: "session", State: "Locked"},
+			Request:  RequestMapping{Method: "GET", Path: CommonMatch{Exact: "/me"}},
+			Response: ResponseMapping{StatusCode: 423},
+			MaxScore: 1,
+			FilePath: "locked.json",
+		},
+	} {
+		handler.AddScenario(m)
+	}
+	return handler
+}
+
+func TestScenarioGraph(t *testing.T) {
+	t.Run("should mark the starting and current states, unreachable states and dead ends", func(t *testing.T) {
+		handler := newSessionScenario(t)
+		_, matched, _ := handler.MatchScenario(Request{Method: "POST", Path: "/login"})
+		require.True(t, matched)
+
+		graph, ok := handler.Graph("session")
+		require.True(t, ok)
+
+		assert.Equal(t, ScenarioGraph{
+			Name:          "session",
+			StartingState: "Logged Out",
+			CurrentState:  "Logged In",
+			States: []ScenarioGraphState{
+				{Name: "Logged Out", Starting: true},
+				{Name: "Locked", Unreachable: true, DeadEnd: true},
+				{Name: "Logged In", Current: true},
+			},
+			Transitions: []ScenarioGraphTransition{
+				{From: "Logged In", To: "Logged Out", Label: "expiry: 30m0s inactive"},
+				{From: "Logged Out", To: "Logged In", Label: "POST /login", MappingFile: "login.json"},
+			},
+		}, graph)
+		assert.Equal(t, []string{"Locked"}, graph.UnreachableStates())
+		assert.Equal(t, []string{"Locked"}, graph.DeadEnds())
+	})
+
+	t.Run("should include the transitions of other mappings", func(t *testing.T) {
+		handler := newSessionScenario(t)
+		handler.AddTriggers(Mapping{
+			Request:     RequestMapping{Method: "POST", Path: CommonMatch{Exact: "/logout"}},
+			Transitions: []ScenarioTrigger{{Scenario: "session", NewState: "Logged Out"}},
+			FilePath:    "logout.json",
+		})
+		handler.AddTriggers(Mapping{
+			Request:     RequestMapping{Method: "POST", Path: CommonMatch{Contains: []string{"lock"}}},
+			Transitions: []ScenarioTrigger{{Scenario: "session", State: "Logged In", NewState: "Locked"}},
+			FilePath:    "lock.json",
+		})
+
+		graph, ok := handler.Graph("session")
+		require.True(t, ok)
+
+		assert.Contains(t, graph.Transitions, ScenarioGraphTransition{From: AnyState, To: "Logged Out", Label: "POST /logout", MappingFile: "logout.json"})
+		assert.Contains(t, graph.Transitions, ScenarioGraphTransition{From: "Logged In", To: "Locked", Label: "POST *lock*", MappingFile: "lock.json"})
+		assert.Empty(t, graph.UnreachableStates())
+		assert.Empty(t, graph.DeadEnds())
+	})
+
+	t.Run("should return false for an unknown scenario", func(t *testing.T) {
+		_, ok := newSessionScenario(t).Graph("unknown")
+		assert.False(t, ok)
+	})
+}
+
+func TestFormatScenarioGraphs(t *testing.T) {
+	graph, _ := newSessionScenario(t).Graph("session")
+
+	t.Run("should write DOT", func(t *testing.T) {
+		out, err := FormatScenarioGraphs([]ScenarioGraph{graph}, GraphFormatDOT)
+		require.NoError(t, err)
+		assert.Equal(t, `digraph "session" {
+  rankdir=LR;
+  node [shape=box, style=rounded];
+  "Logged Out" [peripheries=2, fillcolor=lightblue, style="rounded,filled"];
+  "Locked" [fontcolor=gray, color=red, style="rounded,dashed"];
+  "Logged In" [style="rounded"];
+  "" [shape=point];
+  "" -> "Logged Out";
+  "Logged In" -> "Logged Out" [label="expiry: 30m0s inactive"];
+  "Logged Out" -> "Logged In" [label="POST /login"];
+}
+`, out)
+	})
+
+	t.Run("should write Mermaid", func(t *testing.T) {
+		out, err := FormatScenarioGraphs([]ScenarioGraph{graph}, GraphFormatMermaid)
+		require.NoError(t, err)
+		assert.Equal(t, `stateDiagram-v2
+  %% session
+  state "Logged Out" as s0
+  state "Locked" as s1
+  state "Logged In" as s2
+  [*] --> s0
+  s2 --> s0 : expiry: 30m0s inactive
+  s0 --> s2 : POST /login
+  classDef current fill:#add8e6
+  class s0 current
+  classDef unreachable color:#999,stroke-dasharray:5 5
+  class s1 unreachable
+  classDef deadEnd stroke:#f00
+  class s1 deadEnd
+`, out)
+	})
+
+	t.Run("should escape quotes", func(t *testing.T) {
+		assert.Equal(t, `"say \"hi\""`, dotQuote(`say "hi"`))
+		assert.Equal(t, `"say #quot;hi#quot;"`, mermaidQuote(`say "hi"`))
+	})
+
+	t.Run("should reject unknown formats", func(t *testing.T) {
+		_, err := FormatScenarioGraphs([]ScenarioGraph{graph}, "svg")
+		assert.EqualError(t, err, "unknown graph format 'svg', use 'json', 'dot' or 'mermaid'")
+	})
+}
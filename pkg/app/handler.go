@@ -1,6 +1,10 @@
 package app
 
 import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
 	"strings"
 	"time"
 
@@ -8,19 +12,25 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 	"github.com/ohler55/ojg/oj"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type ServiceMatcher interface {
-	MatchRequest(Request) MatchResult
+	MatchRequest(context.Context, Request) MatchResult
 }
 
 type Request struct {
-	ID      string            `json:"id"`
-	Path    string            `json:"path"`
-	Method  string            `json:"method"`
-	Headers map[string]string `json:"headers"`
-	Body    string            `json:"body"`
-	Date    string            `json:"date"`
+	ID                string            `json:"id"`
+	Scheme            string            `json:"scheme,omitempty"`
+	Host              string            `json:"host,omitempty"`
+	Port              string            `json:"port,omitempty"`
+	ClientCertificate string            `json:"clientCertificate,omitempty"`
+	Path              string            `json:"path"`
+	Method            string            `json:"method"`
+	Headers           map[string]string `json:"headers"`
+	Body              string            `json:"body"`
+	Date              string            `json:"date"`
 }
 
 func RequestFromFiber(r *fiber.Request) Request {
@@ -32,6 +42,8 @@ func RequestFromFiber(r *fiber.Request) Request {
 		Headers: make(map[string]string),
 		Date:    time.Now().Format(time.RFC3339Nano),
 	}
+	req.Scheme = string(r.URI().Scheme())
+	req.Host, req.Port = splitHostPort(string(r.URI().Host()), req.Scheme)
 	r.Header.VisitAll(
 		func(key, value []byte) {
 			req.Headers[strings.ToLower(string(key))] = string(value)
@@ -40,31 +52,94 @@ func RequestFromFiber(r *fiber.Request) Request {
 	return req
 }
 
+// splitHostPort separates the port from the host, falling back to
+// the default port for the scheme when none is present.
+func splitHostPort(hostPort, scheme string) (string, string) {
+	if hostPort == "" {
+		return "", ""
+	}
+
+	host, port, err := net.SplitHostPort(hostPort)
+	if err == nil {
+		return host, port
+	}
+
+	switch scheme {
+	case "https":
+		return hostPort, "443"
+	default:
+		return hostPort, "80"
+	}
+}
+
 type Handler struct {
-	service ServiceMatcher
+	service   ServiceMatcher
+	sockets   *WebSocketHandler
+	tracing   *Tracing
+	accessLog *AccessLog
 }
 
-func NewHandler(service ServiceMatcher) *Handler {
-	return &Handler{service}
+func NewHandler(service ServiceMatcher, sockets *WebSocketHandler, tracing *Tracing, accessLog *AccessLog) *Handler {
+	return &Handler{service, sockets, tracing, accessLog}
 }
 
 func (h Handler) All(c *fiber.Ctx) error {
+	start := time.Now()
 	req := RequestFromFiber(c.Request())
-	res := h.service.MatchRequest(req)
+	req.ClientCertificate = clientCertificateName(c.Context().TLSConnectionState())
+
+	ctx, span := h.tracing.Start(h.tracing.Extract(c.UserContext(), req), "mantis.request",
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(semconv.HTTPRequestMethodKey.String(req.Method), semconv.URLPath(req.Path)),
+	)
+	defer span.End()
+
+	res := h.service.MatchRequest(ctx, req)
+	defer func() { h.accessLog.Log(req, res, time.Since(start)) }()
+	span.SetAttributes(MatchedAttribute.Bool(res.Matched), semconv.HTTPResponseStatusCode(res.StatusCode))
+	if res.Matched {
+		span.SetAttributes(MappingFileAttribute.String(res.Headers["X-Mapping-File"]))
+	}
 
 	if !res.Matched {
-		log.WithFields(log.Fields{
+		fields := log.Fields{
 			"request": req,
 			"result":  res,
-		}).Warn("no match found")
+		}
+		if notFound, ok := res.Body.(NotFoundResponse); ok {
+			nearMisses := make([]string, 0, len(notFound.Candidates))
+			for _, c := range notFound.Candidates {
+				nearMisses = append(nearMisses, c.String())
+			}
+			fields["nearMisses"] = nearMisses
+		}
+		if violation, ok := res.Body.(ContractViolationResponse); ok {
+			violations := make([]string, 0, len(violation.Errors))
+			for _, f := range violation.Errors {
+				violations = append(violations, fmt.Sprintf("%s (%s): %s", f.Field, f.Matcher, f.Reason))
+			}
+			fields["contractViolations"] = violations
+		}
+		log.WithFields(fields).Warn("no match found")
+	}
+
+	if res.Matched && res.WebSocket != nil {
+		return h.sockets.Upgrade(c, res.WebSocket, req)
 	}
 
+	_, writeSpan := h.tracing.Start(ctx, "mantis.response.write")
+	defer writeSpan.End()
+
 	for k, v := range res.Headers {
 		c.Response().Header.Add(k, v)
 	}
 
 	c.Status(res.StatusCode)
 
+	if res.Stream != nil {
+		return sendStream(c, res.Stream)
+	}
+
 	if res.Body != nil {
 		switch b := res.Body.(type) {
 		case string:
@@ -77,6 +152,21 @@ func (h Handler) All(c *fiber.Ctx) error {
 	return c.Send(nil)
 }
 
+// sendStream writes the events of the stream as they become due, until the stream ends or the client disconnects.
+func sendStream(c *fiber.Ctx, stream *EventStream) error {
+	c.Set(fiber.HeaderContentType, EventStreamContentType)
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		if err := stream.Write(w, time.Sleep); err != nil {
+			log.Debugf("event stream closed: %s", err)
+		}
+	})
+
+	return nil
+}
+
 func (Handler) Health(c *fiber.Ctx) error {
 	c.Context().SetContentType("application/json")
 	return c.SendString(`{"status": "ok"}`)
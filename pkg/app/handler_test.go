@@ -1,6 +1,7 @@
 package app
 
 import (
+	"context"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -54,6 +55,40 @@ func TestRequest(t *testing.T) {
 				Headers: map[string]string{"accept": "application/json"},
 			},
 		},
+		{
+			name: "Should build request with host and port",
+			input: func() *fiber.Request {
+				r := &fiber.Request{}
+				r.Header.SetMethod("GET")
+				r.SetRequestURI("https://gophers.local:8443/gopher/2")
+				return r
+			}(),
+			want: Request{
+				Method:  "GET",
+				Scheme:  "https",
+				Host:    "gophers.local",
+				Port:    "8443",
+				Path:    "/gopher/2",
+				Headers: map[string]string{},
+			},
+		},
+		{
+			name: "Should build request with default port",
+			input: func() *fiber.Request {
+				r := &fiber.Request{}
+				r.Header.SetMethod("GET")
+				r.SetRequestURI("http://gophers.local/gopher/2")
+				return r
+			}(),
+			want: Request{
+				Method:  "GET",
+				Scheme:  "http",
+				Host:    "gophers.local",
+				Port:    "80",
+				Path:    "/gopher/2",
+				Headers: map[string]string{},
+			},
+		},
 		{
 			name: "Should build request with no headers",
 			input: func() *fiber.Request {
@@ -76,6 +111,8 @@ func TestRequest(t *testing.T) {
 			got := RequestFromFiber(tt.input)
 			assert.Equal(t, tt.want.Method, got.Method)
 			assert.Equal(t, tt.want.Path, got.Path)
+			assert.Equal(t, tt.want.Host, got.Host)
+			assert.Equal(t, tt.want.Port, got.Port)
 			assert.Equal(t, tt.want.Headers, got.Headers)
 			assert.Equal(t, tt.want.Body, got.Body)
 			_, err := uuid.Parse(got.ID)
@@ -91,7 +128,7 @@ type mockService struct {
 	mockMatchFunc func(Request) MatchResult
 }
 
-func (m mockService) MatchRequest(r Request) MatchResult {
+func (m mockService) MatchRequest(_ context.Context, r Request) MatchResult {
 	return m.mockMatchFunc(r)
 }
 
@@ -139,6 +176,24 @@ func TestHandleResponse(t *testing.T) {
 				assert.Equal(t, "<name>Bilbo</name>", string(body))
 			},
 		},
+		{
+			name: "Should send event stream response",
+			matchFunc: func(r Request) MatchResult {
+				return MatchResult{
+					StatusCode: http.StatusOK,
+					Headers:    map[string]string{},
+					Stream:     &EventStream{Events: []ServerSentEvent{{Event: "token", Data: "Hello"}, {Event: "token", Data: "World"}}},
+				}
+			},
+			assertFunc: func(t *testing.T, r *http.Response) {
+				body, err := io.ReadAll(r.Body)
+				require.NoError(t, err)
+				assert.Equal(t, http.StatusOK, r.StatusCode)
+				assert.Equal(t, "text/event-stream", r.Header.Get("Content-type"))
+				assert.Equal(t, "no-cache", r.Header.Get("Cache-Control"))
+				assert.Equal(t, "event: token\ndata: Hello\n\nevent: token\ndata: World\n\n", string(body))
+			},
+		},
 		{
 			name: "Should send response without body",
 			matchFunc: func(r Request) MatchResult {
@@ -156,7 +211,7 @@ func TestHandleResponse(t *testing.T) {
 
 	for _, tt := range tests {
 		app := fiber.New()
-		hand := NewHandler(mockService{tt.matchFunc})
+		hand := NewHandler(mockService{tt.matchFunc}, nil, NewNoopTracing(), NewAccessLog())
 		app.All("/", hand.All)
 
 		res, err := app.Test(httptest.NewRequest("GET", "/", nil))
@@ -170,7 +225,7 @@ func TestHandleResponse(t *testing.T) {
 
 func TestHealth(t *testing.T) {
 	app := fiber.New()
-	hand := NewHandler(nil)
+	hand := NewHandler(nil, nil, NewNoopTracing(), NewAccessLog())
 	app.Get("/health", hand.Health)
 
 	res, err := app.Test(httptest.NewRequest("GET", "/health", nil))
@@ -0,0 +1,285 @@
+package app
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// harSkippedResponseHeaders describe how the captured response was transferred and are not replayed.
+	harSkippedResponseHeaders = map[string]bool{
+		"connection":        true,
+		"content-encoding":  true,
+		"content-length":    true,
+		"date":              true,
+		"keep-alive":        true,
+		"transfer-encoding": true,
+	}
+)
+
+// HAROptions configures how HAR entries are turned into mappings.
+type HAROptions struct {
+	// Headers are the request headers matched by the mappings, when present in the entry.
+	Headers []string
+
+	// Scenarios turns a request that got different responses over time into a scenario going through
+	// each response, instead of keeping only the first one.
+	Scenarios bool
+
+	// Host matches the host the request was captured from. It is off by default, since clients pointed at
+	// Mantis send its own host instead.
+	Host bool
+}
+
+type harFile struct {
+	Log struct {
+		Entries []harEntry `json:"entries"`
+	} `json:"log"`
+}
+
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+}
+
+type harRequest struct {
+	Method   string      `json:"method"`
+	URL      string      `json:"url"`
+	Headers  []harHeader `json:"headers"`
+	PostData *struct {
+		Text string `json:"text"`
+	} `json:"postData"`
+}
+
+type harResponse struct {
+	Status  int         `json:"status"`
+	Headers []harHeader `json:"headers"`
+	Content struct {
+		MimeType string `json:"mimeType"`
+		Text     string `json:"text"`
+		Encoding string `json:"encoding"`
+	} `json:"content"`
+}
+
+type harHeader struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// harRequestGroup holds the responses of entries with the same request, in the order they were captured.
+type harRequestGroup struct {
+	request   RequestMapping
+	responses []harCapturedResponse
+}
+
+type harCapturedResponse struct {
+	response ResponseMapping
+	mimeType string
+	body     string
+}
+
+func (r harCapturedResponse) equal(other harCapturedResponse) bool {
+	return r.response.StatusCode == other.response.StatusCode && r.body == other.body
+}
+
+// ImportHAR generates mappings from the entries of a HAR file.
+//
+// Each mapping matches the method, path and query string of the request, its body and the configured headers, along
+// with its host when enabled. Entries without a response, like aborted or blocked requests, are skipped. Entries
+// with the same request are de-duplicated, keeping the first response or, with scenarios enabled, turning
+// the distinct responses into the states of a scenario. The import is named 'har' when no name is given, a given
+// name may only contain lowercase letters, digits, '-' and '_'.
+func ImportHAR(content []byte, name string, opts HAROptions) (Import, error) {
+	var file harFile
+	if err := json.Unmarshal(content, &file); err != nil {
+		return Import{}, errors.Wrap(err, "error decoding HAR file")
+	}
+
+	if name == "" {
+		name = "har"
+	}
+	if err := validateImportName(name); err != nil {
+		return Import{}, err
+	}
+
+	entries := file.Log.Entries
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].StartedDateTime < entries[j].StartedDateTime
+	})
+
+	captured := make(map[string]bool, len(opts.Headers))
+	for _, h := range opts.Headers {
+		captured[strings.ToLower(h)] = true
+	}
+
+	var groups []*harRequestGroup
+	byKey := map[string]*harRequestGroup{}
+
+	for i, e := range entries {
+		if e.Response.Status <= 0 {
+			continue
+		}
+
+		req, err := harRequestMapping(e.Request, captured, opts.Host)
+		if err != nil {
+			return Import{}, errors.Wrapf(err, "error importing entry %d", i)
+		}
+
+		res, err := harResponseMapping(e.Response)
+		if err != nil {
+			return Import{}, errors.Wrapf(err, "error importing entry %d", i)
+		}
+
+		key, _ := json.Marshal(req)
+		group, ok := byKey[string(key)]
+		if !ok {
+			group = &harRequestGroup{request: req}
+			byKey[string(key)] = group
+			groups = append(groups, group)
+		}
+
+		if last := len(group.responses) - 1; last < 0 || !group.responses[last].equal(res) {
+			group.responses = append(group.responses, res)
+		}
+	}
+
+	imp := Import{Name: name, Bodies: map[string]string{}}
+
+	used := map[string]bool{}
+	uniqueID := func(base string) string {
+		id := base
+		for n := 2; used[id]; n++ {
+			id = fmt.Sprintf("%s_%d", base, n)
+		}
+		used[id] = true
+		return id
+	}
+
+	for _, group := range groups {
+		id := uniqueID(Slug(group.request.Method + " " + harPath(group.request.Path.Exact)))
+
+		if !opts.Scenarios || len(group.responses) == 1 {
+			imp.Mappings = append(imp.Mappings, imp.harMapping(id, group.request, group.responses[0]))
+			continue
+		}
+
+		for i, res := range group.responses {
+			m := imp.harMapping(uniqueID(fmt.Sprintf("%s_%d", id, i+1)), group.request, res)
+			m.Scenario = &ScenarioMapping{
+				Name:          importID(imp.Name, id),
+				StartingState: i == 0,
+				State:         harState(i),
+			}
+			if i+1 < len(group.responses) {
+				m.Scenario.NewState = harState(i + 1)
+			}
+			imp.Mappings = append(imp.Mappings, m)
+		}
+	}
+
+	return imp, nil
+}
+
+func (imp *Import) harMapping(id string, req RequestMapping, res harCapturedResponse) Mapping {
+	m := Mapping{ID: importID(imp.Name, id), Request: req, Response: res.response}
+
+	if res.body != "" {
+		m.Response.BodyFile = path.Join(imp.Name, Slug(id)+"_response"+harExtension(res.mimeType))
+		imp.Bodies[m.Response.BodyFile] = res.body
+	}
+
+	return m
+}
+
+func harRequestMapping(r harRequest, captured map[string]bool, matchHost bool) (RequestMapping, error) {
+	u, err := url.Parse(r.URL)
+	if err != nil {
+		return RequestMapping{}, errors.Wrapf(err, "invalid url '%s'", r.URL)
+	}
+
+	req := RequestMapping{
+		Method: strings.ToUpper(r.Method),
+		Path:   CommonMatch{Exact: u.RequestURI()},
+	}
+	if host := u.Hostname(); matchHost && host != "" {
+		req.Host = CommonMatch{Exact: strings.ToLower(host)}
+	}
+
+	for _, h := range r.Headers {
+		name := strings.ToLower(h.Name)
+		if !captured[name] {
+			continue
+		}
+		if req.Headers == nil {
+			req.Headers = map[string]CommonMatch{}
+		}
+		req.Headers[name] = CommonMatch{Exact: h.Value}
+	}
+
+	if r.PostData != nil && r.PostData.Text != "" {
+		req.Body = BodyMatch{CommonMatch: CommonMatch{Exact: r.PostData.Text}}
+	}
+
+	return req, nil
+}
+
+func harResponseMapping(r harResponse) (harCapturedResponse, error) {
+	res := harCapturedResponse{
+		response: ResponseMapping{StatusCode: r.Status},
+		mimeType: r.Content.MimeType,
+		body:     r.Content.Text,
+	}
+
+	if r.Content.Encoding == "base64" {
+		body, err := base64.StdEncoding.DecodeString(r.Content.Text)
+		if err != nil {
+			return res, errors.Wrap(err, "error decoding response content")
+		}
+		res.body = string(body)
+	}
+
+	for _, h := range r.Headers {
+		name := strings.ToLower(h.Name)
+		if strings.HasPrefix(name, ":") || harSkippedResponseHeaders[name] {
+			continue
+		}
+		if res.response.Headers == nil {
+			res.response.Headers = map[string]string{}
+		}
+		res.response.Headers[h.Name] = h.Value
+	}
+
+	return res, nil
+}
+
+// harPath returns the path without the query string, used to name the mappings.
+func harPath(requestURI string) string {
+	p, _, _ := strings.Cut(requestURI, "?")
+	return p
+}
+
+func harState(i int) string {
+	return "response " + strconv.Itoa(i+1)
+}
+
+func harExtension(mimeType string) string {
+	switch {
+	case strings.Contains(mimeType, "json"):
+		return ".json"
+	case strings.Contains(mimeType, "html"):
+		return ".html"
+	case strings.Contains(mimeType, "xml"):
+		return ".xml"
+	default:
+		return ".txt"
+	}
+}
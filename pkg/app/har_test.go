@@ -0,0 +1,114 @@
+package app
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportHAR(t *testing.T) {
+	content, err := os.ReadFile("testdata/har/session.har")
+	require.NoError(t, err)
+
+	t.Run("Should keep the first response of duplicated requests", func(t *testing.T) {
+		imp, err := ImportHAR(content, "", HAROptions{})
+		require.NoError(t, err)
+
+		assert.Equal(t, "har", imp.Name)
+		assert.Equal(t, []Mapping{
+			{
+				ID:       "har-get_api_cart",
+				Request:  RequestMapping{Method: "GET", Path: CommonMatch{Exact: "/api/cart?session=1"}},
+				Response: ResponseMapping{StatusCode: 200, Headers: map[string]string{"Content-Type": "application/json"}, BodyFile: "har/get_api_cart_response.json"},
+			},
+			{
+				ID:       "har-post_api_cart_items",
+				Request:  RequestMapping{Method: "POST", Path: CommonMatch{Exact: "/api/cart/items"}, Body: BodyMatch{CommonMatch: CommonMatch{Exact: `{"sku": "123"}`}}},
+				Response: ResponseMapping{StatusCode: 201},
+			},
+		}, imp.Mappings)
+		assert.Equal(t, map[string]string{"har/get_api_cart_response.json": `{"items": []}`}, imp.Bodies)
+	})
+
+	t.Run("Should match headers and turn different responses into a scenario", func(t *testing.T) {
+		imp, err := ImportHAR(content, "cart", HAROptions{Headers: []string{"x-tenant"}, Scenarios: true})
+		require.NoError(t, err)
+
+		require.Len(t, imp.Mappings, 3)
+		tenant := map[string]CommonMatch{"x-tenant": {Exact: "acme"}}
+
+		assert.Equal(t, "cart-get_api_cart_1", imp.Mappings[0].ID)
+		assert.Equal(t, &ScenarioMapping{Name: "cart-get_api_cart", StartingState: true, State: "response 1", NewState: "response 2"}, imp.Mappings[0].Scenario)
+		assert.Equal(t, tenant, imp.Mappings[0].Request.Headers)
+
+		assert.Equal(t, "cart-get_api_cart_2", imp.Mappings[1].ID)
+		assert.Equal(t, &ScenarioMapping{Name: "cart-get_api_cart", State: "response 2"}, imp.Mappings[1].Scenario)
+
+		assert.Equal(t, "cart-post_api_cart_items", imp.Mappings[2].ID)
+		assert.Nil(t, imp.Mappings[2].Scenario)
+		assert.Equal(t, tenant, imp.Mappings[2].Request.Headers)
+
+		assert.Equal(t, map[string]string{
+			"cart/get_api_cart_1_response.json": `{"items": []}`,
+			"cart/get_api_cart_2_response.json": `{"items": ["123"]}`,
+		}, imp.Bodies)
+	})
+
+	t.Run("Should load the imported scenario", func(t *testing.T) {
+		imp, err := ImportHAR(content, "cart", HAROptions{Scenarios: true})
+		require.NoError(t, err)
+
+		mappingsPath, responsesPath := t.TempDir(), t.TempDir()
+		_, err = NewImporterWithPaths(mappingsPath, responsesPath).Write(imp)
+		require.NoError(t, err)
+
+		regexCache := NewRegexCache()
+		jsonPathCache := NewJSONPathCache()
+		scenarioHandler := NewScenarioHandler(NewMatcher(regexCache, jsonPathCache))
+		_, err = NewLoader(regexCache, jsonPathCache, scenarioHandler).LoadMappings(mappingsPath, responsesPath)
+		require.NoError(t, err)
+
+		r := Request{Host: "shop.example.com", Method: "GET", Path: "/api/cart?session=1"}
+		first, _, _ := scenarioHandler.MatchScenario(r)
+		second, _, _ := scenarioHandler.MatchScenario(r)
+		third, _, _ := scenarioHandler.MatchScenario(r)
+
+		assert.Equal(t, `{"items": []}`, first.Response.Body)
+		assert.Equal(t, `{"items": ["123"]}`, second.Response.Body)
+		assert.Equal(t, `{"items": ["123"]}`, third.Response.Body)
+	})
+
+	t.Run("Should keep the requests to different hosts apart when matching hosts", func(t *testing.T) {
+		imp, err := ImportHAR([]byte(`{"log": {"entries": [
+			{"request": {"method": "GET", "url": "https://a.example.com/status"}, "response": {"status": 200}},
+			{"request": {"method": "GET", "url": "https://B.example.com/status"}, "response": {"status": 503}}
+		]}}`), "", HAROptions{Host: true})
+		require.NoError(t, err)
+
+		require.Len(t, imp.Mappings, 2)
+		assert.Equal(t, "har-get_status", imp.Mappings[0].ID)
+		assert.Equal(t, CommonMatch{Exact: "a.example.com"}, imp.Mappings[0].Request.Host)
+		assert.Equal(t, "har-get_status_2", imp.Mappings[1].ID)
+		assert.Equal(t, CommonMatch{Exact: "b.example.com"}, imp.Mappings[1].Request.Host)
+	})
+
+	t.Run("Should skip entries without a response", func(t *testing.T) {
+		imp, err := ImportHAR([]byte(`{"log": {"entries": [
+			{"request": {"method": "GET", "url": "https://shop.example.com/blocked"}, "response": {"status": 0}},
+			{"request": {"method": "GET", "url": "https://shop.example.com/status"}, "response": {"status": 200}}
+		]}}`), "", HAROptions{})
+		require.NoError(t, err)
+
+		require.Len(t, imp.Mappings, 1)
+		assert.Equal(t, "har-get_status", imp.Mappings[0].ID)
+		assert.Equal(t, CommonMatch{}, imp.Mappings[0].Request.Host)
+		assert.Equal(t, 200, imp.Mappings[0].Response.StatusCode)
+	})
+
+	t.Run("Should fail for invalid files", func(t *testing.T) {
+		_, err := ImportHAR([]byte(`{"log": {"entries": [{"request": {"url": "http://a b"}, "response": {"status": 200}}]}}`), "", HAROptions{})
+		assert.EqualError(t, err, `error importing entry 0: invalid url 'http://a b': parse "http://a b": invalid character " " in host name`)
+	})
+}
@@ -0,0 +1,190 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/americanas-go/config"
+	"github.com/pkg/errors"
+)
+
+const (
+	ImportNameMessage = "import name '%s' must only contain lowercase letters, digits, '-' and '_'"
+)
+
+var (
+	slugRegex       = regexp.MustCompile(`[^a-z0-9]+`)
+	importNameRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+)
+
+// Import is a set of mappings generated from another format, written by the Importer
+// into a folder with the import name.
+type Import struct {
+	Name     string
+	Mappings []Mapping
+
+	// Bodies holds the content of the response body files referenced by the mappings,
+	// keyed by the body file name.
+	Bodies map[string]string
+}
+
+// Importer writes imported mappings using the folder layout read by the Loader.
+type Importer struct {
+	mappingsPath  string
+	responsesPath string
+}
+
+func NewImporter() *Importer {
+	return NewImporterWithPaths(config.String("loader.path.mapping"), config.String("loader.path.response"))
+}
+
+func NewImporterWithPaths(mappingsPath, responsesPath string) *Importer {
+	return &Importer{mappingsPath, responsesPath}
+}
+
+// Write writes each mapping to its own file, named after the mapping ID, inside the import folder of
+// the mappings path, and the response bodies inside the import folder of the responses path.
+//
+// Existing files are overwritten. The paths of the files written are returned.
+func (i *Importer) Write(imp Import) ([]string, error) {
+	written := make([]string, 0, len(imp.Mappings)+len(imp.Bodies))
+
+	for _, m := range imp.Mappings {
+		content, err := MarshalMapping(m)
+		if err != nil {
+			return written, errors.Wrapf(err, "error encoding mapping '%s'", m.ID)
+		}
+
+		path := filepath.Join(i.mappingsPath, imp.Name, Slug(strings.TrimPrefix(m.ID, imp.Name+"-"))+".json")
+		if err := insideFolder(i.mappingsPath, path); err != nil {
+			return written, err
+		}
+		if err := writeFile(path, content); err != nil {
+			return written, err
+		}
+		written = append(written, path)
+	}
+
+	for file, body := range imp.Bodies {
+		path := filepath.Join(i.responsesPath, file)
+		if err := insideFolder(i.responsesPath, path); err != nil {
+			return written, err
+		}
+		if err := writeFile(path, []byte(body)); err != nil {
+			return written, err
+		}
+		written = append(written, path)
+	}
+
+	return written, nil
+}
+
+// importID namespaces the ID of an imported mapping by the import name, so it doesn't clash with the mappings of
+// other imports.
+func importID(name, id string) string {
+	return name + "-" + id
+}
+
+// insideFolder returns an error when the path is not inside the folder, so an import can't write anywhere else.
+func insideFolder(folder, path string) error {
+	rel, err := filepath.Rel(folder, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) || filepath.IsAbs(rel) {
+		return errors.Errorf("file '%s' is outside of the folder '%s'", path, folder)
+	}
+	return nil
+}
+
+// validateImportName checks the name given to an import, which names the folders the files are written to.
+func validateImportName(name string) error {
+	if !importNameRegex.MatchString(name) {
+		return errors.Errorf(ImportNameMessage, name)
+	}
+	return nil
+}
+
+func writeFile(path string, content []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return errors.Wrapf(err, "error creating folder for file '%s'", path)
+	}
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		return errors.Wrapf(err, "error writing file '%s'", path)
+	}
+	return nil
+}
+
+// MarshalMapping encodes the mapping as indented JSON, leaving out empty fields.
+func MarshalMapping(m Mapping) ([]byte, error) {
+	content, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+
+	var value any
+	if err := json.Unmarshal(content, &value); err != nil {
+		return nil, err
+	}
+
+	return marshalIndent(prune(value))
+}
+
+// marshalIndent encodes the value as indented JSON, without escaping HTML characters.
+func marshalIndent(value any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(value); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// prune removes nulls, zero values and empty objects and arrays, returning nil if nothing is left.
+func prune(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for k, e := range v {
+			if p := prune(e); p != nil {
+				v[k] = p
+			} else {
+				delete(v, k)
+			}
+		}
+		if len(v) == 0 {
+			return nil
+		}
+		return v
+	case []any:
+		if len(v) == 0 {
+			return nil
+		}
+		for i, e := range v {
+			if p := prune(e); p != nil {
+				v[i] = p
+			}
+		}
+		return v
+	case string:
+		if v == "" {
+			return nil
+		}
+	case bool:
+		if !v {
+			return nil
+		}
+	case float64:
+		if v == 0 {
+			return nil
+		}
+	}
+	return value
+}
+
+// Slug turns the value into a lowercase name with words separated by underscores, to be used in file names.
+func Slug(value string) string {
+	return strings.Trim(slugRegex.ReplaceAllString(strings.ToLower(value), "_"), "_")
+}
@@ -0,0 +1,92 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImporterWrite(t *testing.T) {
+	mappingsPath, responsesPath := t.TempDir(), t.TempDir()
+
+	imp := Import{
+		Name: "orders",
+		Mappings: []Mapping{
+			{
+				ID:       "orders-getOrder",
+				Request:  RequestMapping{Method: "GET", Path: CommonMatch{Patterns: []string{`^/orders/[0-9]+$`}}},
+				Response: ResponseMapping{StatusCode: 200, BodyFile: "orders/getorder_response.json"},
+			},
+		},
+		Bodies: map[string]string{"orders/getorder_response.json": `{"id": 1}`},
+	}
+
+	written, err := NewImporterWithPaths(mappingsPath, responsesPath).Write(imp)
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, []string{
+		filepath.Join(mappingsPath, "orders", "getorder.json"),
+		filepath.Join(responsesPath, "orders", "getorder_response.json"),
+	}, written)
+
+	content, err := os.ReadFile(filepath.Join(mappingsPath, "orders", "getorder.json"))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"id": "orders-getOrder",
+		"request": {"method": "GET", "path": {"pattern": ["^/orders/[0-9]+$"]}},
+		"response": {"statusCode": 200, "bodyFile": "orders/getorder_response.json"}
+	}`, string(content))
+
+	regexCache := NewRegexCache()
+	jsonPathCache := NewJSONPathCache()
+	matcher := NewMatcher(regexCache, jsonPathCache)
+	mappings, err := NewLoader(regexCache, jsonPathCache, NewScenarioHandler(matcher)).LoadMappings(mappingsPath, responsesPath)
+	require.NoError(t, err)
+
+	mapping, matched, _ := matcher.Match(Request{Method: "GET", Path: "/orders/12"}, mappings, nil)
+	assert.True(t, matched)
+	assert.Equal(t, `{"id": 1}`, mapping.Response.Body)
+}
+
+func TestImporterWriteOutsideFolders(t *testing.T) {
+	root := t.TempDir()
+	mappingsPath, responsesPath := filepath.Join(root, "mapping"), filepath.Join(root, "response")
+	importer := NewImporterWithPaths(mappingsPath, responsesPath)
+
+	_, err := importer.Write(Import{Name: "../..", Mappings: []Mapping{{ID: "escape"}}})
+	assert.ErrorContains(t, err, "is outside of the folder")
+
+	_, err = importer.Write(Import{Name: "orders", Bodies: map[string]string{"../../escape.json": "{}"}})
+	assert.ErrorContains(t, err, "is outside of the folder")
+
+	assert.NoFileExists(t, filepath.Join(root, "escape.json"))
+	assert.NoFileExists(t, filepath.Join(filepath.Dir(root), "escape.json"))
+}
+
+func TestMarshalMapping(t *testing.T) {
+	content, err := MarshalMapping(Mapping{
+		Request:  RequestMapping{Method: "GET", Path: CommonMatch{Exact: "/a?b=1&c=2"}},
+		Response: ResponseMapping{StatusCode: 200, ResponseDelay: Delay{Fixed: FixedDelay{Duration: Duration(1500000000)}}},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, `{
+  "request": {
+    "method": "GET",
+    "path": {
+      "exact": "/a?b=1&c=2"
+    }
+  },
+  "response": {
+    "delay": {
+      "fixed": {
+        "duration": "1.5s"
+      }
+    },
+    "statusCode": 200
+  }
+}`, string(content))
+}
@@ -0,0 +1,91 @@
+package app
+
+import (
+	"sync"
+
+	"github.com/americanas-go/config"
+)
+
+const (
+	DefaultJournalMaxEntries = 1000
+)
+
+type JournalEntry struct {
+	Request     Request            `json:"request"`
+	Matched     bool               `json:"matched"`
+	StatusCode  int                `json:"statusCode"`
+	MappingFile string             `json:"mappingFile,omitempty"`
+	Messages    []WebSocketMessage `json:"messages,omitempty"`
+}
+
+// Journal keeps the most recent requests received by Mantis along with their match outcome.
+type Journal struct {
+	mu         sync.RWMutex
+	entries    []JournalEntry
+	maxEntries int
+}
+
+func NewJournal() *Journal {
+	maxEntries := config.Int("journal.maxEntries")
+	if maxEntries <= 0 {
+		maxEntries = DefaultJournalMaxEntries
+	}
+
+	return &Journal{
+		entries:    make([]JournalEntry, 0),
+		maxEntries: maxEntries,
+	}
+}
+
+// Record adds an entry to the journal, discarding the oldest one if the journal is full.
+func (j *Journal) Record(entry JournalEntry) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if len(j.entries) >= j.maxEntries {
+		j.entries = j.entries[1:]
+	}
+	j.entries = append(j.entries, entry)
+}
+
+// RecordMessage adds a WebSocket message to the entry of the request that started the session.
+func (j *Journal) RecordMessage(requestID string, message WebSocketMessage) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for i := len(j.entries) - 1; i >= 0; i-- {
+		if j.entries[i].Request.ID == requestID {
+			j.entries[i].Messages = append(j.entries[i].Messages, message)
+			return
+		}
+	}
+}
+
+func (j *Journal) Entries() []JournalEntry {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	entries := make([]JournalEntry, len(j.entries))
+	copy(entries, j.entries)
+	return entries
+}
+
+func (j *Journal) Unmatched() []JournalEntry {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	entries := make([]JournalEntry, 0)
+	for _, e := range j.entries {
+		if !e.Matched {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+func (j *Journal) Reset() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.entries = make([]JournalEntry, 0)
+}
@@ -0,0 +1,23 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJournal(t *testing.T) {
+	journal := NewJournal()
+	journal.maxEntries = 2
+
+	journal.Record(JournalEntry{Request: Request{ID: "1"}, Matched: true})
+	journal.Record(JournalEntry{Request: Request{ID: "2"}, Matched: false})
+	assert.Equal(t, []JournalEntry{{Request: Request{ID: "1"}, Matched: true}, {Request: Request{ID: "2"}}}, journal.Entries())
+
+	journal.Record(JournalEntry{Request: Request{ID: "3"}, Matched: false})
+	assert.Equal(t, []JournalEntry{{Request: Request{ID: "2"}}, {Request: Request{ID: "3"}}}, journal.Entries())
+	assert.Equal(t, []JournalEntry{{Request: Request{ID: "2"}}, {Request: Request{ID: "3"}}}, journal.Unmatched())
+
+	journal.Reset()
+	assert.Empty(t, journal.Entries())
+}
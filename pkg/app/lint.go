@@ -0,0 +1,263 @@
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// LintIssue is a problem found in a mapping file.
+type LintIssue struct {
+	File    string
+	Message string
+}
+
+func (i LintIssue) String() string {
+	return fmt.Sprintf("%s: %s", i.File, i.Message)
+}
+
+// LintReport holds every error and warning found while validating the mapping files.
+type LintReport struct {
+	Errors   []LintIssue
+	Warnings []LintIssue
+}
+
+func (r LintReport) Valid() bool {
+	return len(r.Errors) == 0
+}
+
+func (r *LintReport) addError(file string, format string, args ...any) {
+	r.Errors = append(r.Errors, LintIssue{file, fmt.Sprintf(format, args...)})
+}
+
+func (r *LintReport) addWarning(file string, format string, args ...any) {
+	r.Warnings = append(r.Warnings, LintIssue{file, fmt.Sprintf(format, args...)})
+}
+
+// Lint runs the same steps used to load the mappings, but instead of stopping at the first error
+// it reports every error found, along with warnings for mappings that can never be matched.
+func (loader *Loader) Lint(mappingsPath string, responsesPath string) LintReport {
+	var report LintReport
+
+	ids := make(map[string]string)
+	scenarioFiles := make(map[string]string)
+	triggerFiles := make(map[string]string)
+	scenarios := NewScenarioHandler(nil)
+	var valid []Mapping
+
+	_ = filepath.WalkDir(
+		mappingsPath,
+		func(filePath string, d fs.DirEntry, err error) error {
+			if err != nil {
+				report.addError(filePath, "%s", err)
+				return nil
+			}
+			if d.IsDir() || IsSchemaFile(filePath) {
+				return nil
+			}
+
+			loaded, warnings, err := loader.decodeMapping(filePath)
+			if err != nil {
+				report.addError(filePath, "%s", err)
+				return nil
+			}
+			for _, w := range warnings {
+				report.addWarning(filePath, "%s", w)
+			}
+
+			host := hostFromPath(mappingsPath, filePath)
+
+			for i, mapping := range loaded {
+				prepareMapping(&mapping, mappingsPath, filePath, host, i)
+				name := fmt.Sprintf("mapping '%s'", mapping.Key())
+				ok := true
+
+				if other, found := ids[mapping.ID]; found {
+					report.addError(filePath, "%s", DuplicateMappingID(mapping.ID, filePath, other))
+					ok = false
+				} else {
+					ids[mapping.ID] = filePath
+				}
+
+				if err := mapping.Validate(); err != nil {
+					var validationErrs ValidationErrors
+					if errors.As(err, &validationErrs) {
+						for _, e := range validationErrs {
+							report.addError(filePath, "%s: %s: %s", name, e.Field, e.Message)
+						}
+					} else {
+						report.addError(filePath, "%s: %s", name, err)
+					}
+					ok = false
+				}
+
+				if err := loader.processMapping(&mapping, filePath, responsesPath); err != nil {
+					report.addError(filePath, "%s: %s", name, err)
+					ok = false
+				}
+
+				for _, t := range mapping.Transitions {
+					if _, found := triggerFiles[t.Scenario]; !found {
+						triggerFiles[t.Scenario] = filePath
+					}
+				}
+				scenarios.AddTriggers(mapping)
+
+				if mapping.Scenario != nil {
+					if _, found := scenarioFiles[mapping.Scenario.Name]; !found {
+						scenarioFiles[mapping.Scenario.Name] = filePath
+					}
+					scenarios.AddScenario(mapping)
+					continue
+				}
+
+				if ok {
+					valid = append(valid, mapping)
+				}
+			}
+
+			return nil
+		},
+	)
+
+	var scenarioErrs ScenarioValidationErrors
+	if errors.As(scenarios.ValidateScenarioStates(), &scenarioErrs) {
+		sort.SliceStable(scenarioErrs, func(i, j int) bool {
+			return scenarioErrs[i].ScenarioName < scenarioErrs[j].ScenarioName
+		})
+		for _, e := range scenarioErrs {
+			file, found := scenarioFiles[e.ScenarioName]
+			if !found {
+				file = triggerFiles[e.ScenarioName]
+			}
+			report.addError(file, "scenario '%s': %s", e.ScenarioName, e.Message)
+		}
+	}
+
+	invalid := make(map[string]bool)
+	for _, e := range scenarioErrs {
+		invalid[e.ScenarioName] = true
+	}
+	for _, g := range scenarios.Graphs() {
+		if invalid[g.Name] {
+			continue
+		}
+		for _, state := range g.UnreachableStates() {
+			report.addWarning(scenarioFiles[g.Name], "scenario '%s': "+ScenarioUnreachableStateMessage, g.Name, state)
+		}
+		for _, state := range g.DeadEnds() {
+			report.addWarning(scenarioFiles[g.Name], "scenario '%s': "+ScenarioDeadEndMessage, g.Name, state)
+		}
+	}
+
+	loader.lintUnreachable(valid, &report)
+
+	return report
+}
+
+// lintUnreachable warns about mappings that can never be matched, because a mapping loaded
+// before them matches every request they do.
+func (loader *Loader) lintUnreachable(mappings []Mapping, report *LintReport) {
+	matcher := NewMatcher(loader.regexCache, loader.jsonPathCache)
+
+	for j, m := range mappings {
+		for _, other := range mappings[:j] {
+			if other.Request.Method != m.Request.Method {
+				continue
+			}
+
+			if reflect.DeepEqual(other.Request, m.Request) {
+				report.addWarning(m.FilePath, "mapping '%s' has the same request as mapping '%s' in [ %s ] and will never be matched", m.Key(), other.Key(), other.FilePath)
+				break
+			}
+
+			if shadows(matcher, other.Request, m.Request) {
+				report.addWarning(m.FilePath, "mapping '%s' is shadowed by mapping '%s' in [ %s ] and will never be matched", m.Key(), other.Key(), other.FilePath)
+				break
+			}
+		}
+	}
+}
+
+// shadows reports whether every request matched by b is also matched by a.
+//
+// The check is conservative, conditions that can't be compared are considered as not shadowing.
+func shadows(matcher *Matcher, a, b RequestMapping) bool {
+	if a.Scheme != "" && !strings.EqualFold(a.Scheme, b.Scheme) {
+		return false
+	}
+
+	if a.Port != "" && a.Port != b.Port {
+		return false
+	}
+
+	if !coversHost(matcher, a.Host, b.Host) ||
+		!covers(matcher, BodyMatch{CommonMatch: a.ClientCertificate}, BodyMatch{CommonMatch: b.ClientCertificate}) ||
+		!covers(matcher, BodyMatch{CommonMatch: a.Path}, BodyMatch{CommonMatch: b.Path}) ||
+		!covers(matcher, a.Body, b.Body) {
+		return false
+	}
+
+	for k, v := range a.Headers {
+		if !covers(matcher, BodyMatch{CommonMatch: v}, BodyMatch{CommonMatch: b.Headers[k]}) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// coversHost reports whether every host matched by b is also matched by a, ignoring case like the matcher.
+func coversHost(matcher *Matcher, a, b CommonMatch) bool {
+	if b.Exact != "" && !a.IsEmpty() {
+		return matcher.matchHost(Request{Host: b.Exact}, Mapping{Request: RequestMapping{Host: a}})
+	}
+	return covers(matcher, BodyMatch{CommonMatch: a}, BodyMatch{CommonMatch: b})
+}
+
+// covers reports whether every value matched by b is also matched by a.
+func covers(matcher *Matcher, a, b BodyMatch) bool {
+	if a.IsEmpty() && len(a.JsonPath) == 0 && !a.HasSchema() {
+		return true
+	}
+
+	if b.Exact != "" {
+		return matcher.MatchBody(a, b.Exact)
+	}
+
+	if a.Exact != "" {
+		return false
+	}
+
+	return containsAll(b.Contains, a.Contains) && containsAll(b.Patterns, a.Patterns) && containsAll(b.JsonPath, a.JsonPath) && sameSchema(a, b)
+}
+
+// sameSchema reports whether b uses the same schema as a, when a has one.
+func sameSchema(a, b BodyMatch) bool {
+	if !a.HasSchema() {
+		return true
+	}
+	return a.SchemaFile == b.SchemaFile && bytes.Equal(a.Schema, b.Schema)
+}
+
+func containsAll(values []string, wanted []string) bool {
+	for _, w := range wanted {
+		found := false
+		for _, v := range values {
+			if v == w {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,103 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLint(t *testing.T) {
+	tests := []struct {
+		name          string
+		mappingsPath  string
+		responsesPath string
+		want          LintReport
+	}{
+		{
+			name:          "Should only warn about dead ends for valid mappings",
+			mappingsPath:  "testdata/load/valid/mapping",
+			responsesPath: "testdata/load/valid/response",
+			want: LintReport{
+				Warnings: []LintIssue{
+					{"testdata/load/valid/mapping/post_scenario_start.json", "scenario 'My Scenario': the scenario never leaves the state [Second state]"},
+				},
+			},
+		},
+		{
+			name:          "Should report every error and warning found",
+			mappingsPath:  "testdata/lint/mapping",
+			responsesPath: "testdata/lint/response",
+			want: LintReport{
+				Errors: []LintIssue{
+					{"testdata/lint/mapping/a_invalid.json", "mapping '89dcc025aa35': Request.Method: Method is required"},
+					{"testdata/lint/mapping/b_regex.json", "mapping 'bad-regex': error adding mapping from: failed to compile path regex with pattern:  /regex/[0-9 : error parsing regexp: missing closing ]: `[0-9`"},
+					{"testdata/lint/mapping/b_regex.json", "duplicate mapping id 'bad-regex' in files [ testdata/lint/mapping/b_regex.json ] and [ testdata/lint/mapping/b_regex.json ]"},
+					{"testdata/lint/mapping/c_body_file.json", "mapping 'missing-body-file': error loading response body file for mapping: file 'testdata/lint/response/missing.json' not found"},
+					{"testdata/lint/mapping/e_syntax.json", "unexpected end of JSON input"},
+					{"testdata/lint/mapping/d_scenario.json", "scenario 'lonely': " + ScenarioSingleStateMessage},
+				},
+				Warnings: []LintIssue{
+					{"testdata/lint/mapping/get_users_copy.json", "mapping 'users-copy' has the same request as mapping 'users' in [ testdata/lint/mapping/get_users.json ] and will never be matched"},
+					{"testdata/lint/mapping/get_users_json.json", "mapping 'users-json' is shadowed by mapping 'users' in [ testdata/lint/mapping/get_users.json ] and will never be matched"},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			regexCache := NewRegexCache()
+			jsonPathCache := NewJSONPathCache()
+			loader := NewLoader(regexCache, jsonPathCache, NewScenarioHandler(NewMatcher(regexCache, jsonPathCache)))
+
+			report := loader.Lint(tt.mappingsPath, tt.responsesPath)
+
+			assert.Equal(t, tt.want, report)
+			assert.Equal(t, len(tt.want.Errors) == 0, report.Valid())
+		})
+	}
+}
+
+func TestShadows(t *testing.T) {
+	tests := []struct {
+		name string
+		a    RequestMapping
+		b    RequestMapping
+		want bool
+	}{
+		{
+			name: "Should shadow when the path pattern matches the exact path",
+			a:    RequestMapping{Method: "GET", Path: CommonMatch{Patterns: []string{"/users/[0-9]+"}}},
+			b:    RequestMapping{Method: "GET", Path: CommonMatch{Exact: "/users/123"}},
+			want: true,
+		},
+		{
+			name: "Should shadow when the conditions are a subset",
+			a:    RequestMapping{Method: "GET", Path: CommonMatch{Contains: []string{"/users"}}},
+			b:    RequestMapping{Method: "GET", Port: "8080", Path: CommonMatch{Contains: []string{"/users", "/active"}}},
+			want: true,
+		},
+		{
+			name: "Should not shadow when the header is not required by the other mapping",
+			a:    RequestMapping{Method: "GET", Path: CommonMatch{Exact: "/users"}, Headers: map[string]CommonMatch{"Accept": {Exact: "application/json"}}},
+			b:    RequestMapping{Method: "GET", Path: CommonMatch{Exact: "/users"}},
+			want: false,
+		},
+		{
+			name: "Should not shadow when the hosts differ",
+			a:    RequestMapping{Method: "GET", Host: CommonMatch{Exact: "a.local"}, Path: CommonMatch{Exact: "/users"}},
+			b:    RequestMapping{Method: "GET", Host: CommonMatch{Exact: "b.local"}, Path: CommonMatch{Exact: "/users"}},
+			want: false,
+		},
+	}
+
+	regexCache := NewRegexCache()
+	matcher := NewMatcher(regexCache, NewJSONPathCache())
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_ = regexCache.AddFromMapping(Mapping{Request: tt.a})
+			assert.Equal(t, tt.want, shadows(matcher, tt.a, tt.b))
+		})
+	}
+}
@@ -6,12 +6,18 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"strings"
 
 	"github.com/americanas-go/config"
 	"github.com/americanas-go/log"
 	"github.com/pkg/errors"
 )
 
+const (
+	// HostFolderPrefix marks a mapping folder as scoped to a host, e.g. '@api.example.com'.
+	HostFolderPrefix = "@"
+)
+
 var (
 	spaceRegex = regexp.MustCompile(`\s*(.*)\n`)
 )
@@ -20,20 +26,34 @@ func FileNotFound(path string) error {
 	return errors.Errorf("file '%s' not found", path)
 }
 
+func DuplicateMappingID(id, filePath, otherFilePath string) error {
+	return errors.Errorf("duplicate mapping id '%s' in files [ %s ] and [ %s ]", id, filePath, otherFilePath)
+}
+
 type Loader struct {
 	regexCache      *RegexCache
 	jsonPathCache   *JSONPathCache
+	schemaCache     *SchemaCache
 	scenarioHandler *ScenarioHandler
+	wireMock        bool
 }
 
 func NewLoader(regexCache *RegexCache, jsonPathCache *JSONPathCache, scenarioHandler *ScenarioHandler) *Loader {
-	return &Loader{regexCache, jsonPathCache, scenarioHandler}
+	return &Loader{
+		regexCache:      regexCache,
+		jsonPathCache:   jsonPathCache,
+		schemaCache:     NewSchemaCache(),
+		scenarioHandler: scenarioHandler,
+		wireMock:        config.Bool("loader.wiremock.enabled"),
+	}
 }
 
 func (loader *Loader) GetMappings() (Mappings, error) {
-	mappingsPath := config.String("loader.path.mapping")
-	responsesPath := config.String("loader.path.response")
+	return loader.LoadMappings(config.String("loader.path.mapping"), config.String("loader.path.response"))
+}
 
+// LoadMappings loads the mappings from the given folders, adding the scenario mappings to the scenario handler.
+func (loader *Loader) LoadMappings(mappingsPath string, responsesPath string) (Mappings, error) {
 	mappings := make(Mappings)
 	err := loader.loadMappings(mappingsPath, responsesPath, mappings)
 	if err != nil {
@@ -44,22 +64,36 @@ func (loader *Loader) GetMappings() (Mappings, error) {
 }
 
 func (loader *Loader) loadMappings(mappingsPath string, responsesPath string, mappings Mappings) error {
+	ids := make(map[string]string)
+
 	err := filepath.WalkDir(
 		mappingsPath,
 		func(filePath string, d fs.DirEntry, err error) error {
-			if d != nil && !d.IsDir() {
+			if d != nil && !d.IsDir() && !IsSchemaFile(filePath) {
 				log.Debugf("reading file '%s'", filePath)
-				loaded, err := loader.decodeMapping(filePath)
+				loaded, warnings, err := loader.decodeMapping(filePath)
 				if err != nil {
 					return err
 				}
+				for _, w := range warnings {
+					log.Warnf("file [ %s ]: %s", filePath, w)
+				}
+
+				host := hostFromPath(mappingsPath, filePath)
+
+				for i, mapping := range loaded {
+					prepareMapping(&mapping, mappingsPath, filePath, host, i)
+					if other, ok := ids[mapping.ID]; ok {
+						return DuplicateMappingID(mapping.ID, filePath, other)
+					}
+					ids[mapping.ID] = filePath
 
-				for _, mapping := range loaded {
 					err := loader.processMapping(&mapping, filePath, responsesPath)
 					if err != nil {
 						return errors.Wrapf(err, "error processing file [ %s ]", filePath)
 					}
 
+					loader.scenarioHandler.AddTriggers(mapping)
 					if mapping.Scenario != nil {
 						loader.scenarioHandler.AddScenario(mapping)
 					} else {
@@ -88,10 +122,25 @@ func (loader *Loader) loadMappings(mappingsPath string, responsesPath string, ma
 	return nil
 }
 
-func (*Loader) decodeMapping(path string) ([]Mapping, error) {
+// decodeMapping decodes the mappings of a file, converting WireMock stubs when enabled.
+// The WireMock features that couldn't be converted are returned as warnings.
+func (loader *Loader) decodeMapping(path string) ([]Mapping, []string, error) {
 	content, err := loadFile(path)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	if IsYAMLFile(path) {
+		mappings, err := decodeYAMLMappings(path, content)
+		return mappings, nil, err
+	}
+
+	if loader.wireMock && IsWireMockStub(content) {
+		mappings, warnings, err := ConvertWireMock(content)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "error decoding file '%s'", path)
+		}
+		return mappings, warnings, nil
 	}
 
 	var mappings []Mapping
@@ -100,7 +149,7 @@ func (*Loader) decodeMapping(path string) ([]Mapping, error) {
 		var m Mapping
 		err = json.Unmarshal(content, &m)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		mappings = append(mappings, m)
 	}
@@ -108,7 +157,46 @@ func (*Loader) decodeMapping(path string) ([]Mapping, error) {
 		m.FilePath = path
 	}
 
-	return mappings, nil
+	return mappings, nil, nil
+}
+
+// prepareMapping sets the values derived from the location of the mapping: its index in the file,
+// the generated ID when none is given and the host of the folder it is in.
+func prepareMapping(mapping *Mapping, mappingsPath, filePath, host string, index int) {
+	mapping.Index = index
+	if mapping.ID == "" {
+		mapping.ID = GenerateMappingID(relativePath(mappingsPath, filePath), index)
+	}
+
+	if host != "" && !mapping.Request.HasHost() {
+		mapping.Request.Host = CommonMatch{Exact: host}
+	}
+}
+
+// hostFromPath returns the host of the innermost host-scoped folder containing the file,
+// or an empty string if the file is not inside one.
+func hostFromPath(mappingsPath, filePath string) string {
+	rel, err := filepath.Rel(mappingsPath, filepath.Dir(filePath))
+	if err != nil {
+		return ""
+	}
+
+	var host string
+	for _, dir := range strings.Split(filepath.ToSlash(rel), "/") {
+		if strings.HasPrefix(dir, HostFolderPrefix) {
+			host = strings.TrimPrefix(dir, HostFolderPrefix)
+		}
+	}
+
+	return host
+}
+
+func relativePath(basePath, filePath string) string {
+	rel, err := filepath.Rel(basePath, filePath)
+	if err != nil {
+		return filePath
+	}
+	return rel
 }
 
 func loadFile(path string) ([]byte, error) {
@@ -123,15 +211,19 @@ func loadFile(path string) ([]byte, error) {
 }
 
 func (loader Loader) processMapping(mapping *Mapping, filePath, responsesPath string) error {
-	if mapping.Response.BodyFile != "" {
-		bodyContent, err := loadFile(filepath.Join(responsesPath, mapping.Response.BodyFile))
+	err := loadResponseBody(&mapping.Response, responsesPath)
+	if err != nil {
+		return err
+	}
+
+	for i := range mapping.Responses {
+		err = loadResponseBody(&mapping.Responses[i], responsesPath)
 		if err != nil {
-			return errors.Wrap(err, "error loading response body file for mapping")
+			return err
 		}
-		mapping.Response.Body = spaceRegex.ReplaceAllString(string(bodyContent), "$1")
 	}
 
-	err := loader.regexCache.AddFromMapping(*mapping)
+	err = loader.regexCache.AddFromMapping(*mapping)
 	if err != nil {
 		return errors.Wrap(err, "error adding mapping from")
 	}
@@ -141,8 +233,48 @@ func (loader Loader) processMapping(mapping *Mapping, filePath, responsesPath st
 		return errors.Wrap(err, "error adding mapping from")
 	}
 
+	err = loader.schemaCache.Compile(&mapping.Request.Body, filePath)
+	if err != nil {
+		return errors.Wrap(err, "error adding mapping from")
+	}
+
+	if mapping.Store != nil {
+		err = mapping.Store.Key.compile()
+		if err != nil {
+			return errors.Wrap(err, "error adding mapping from")
+		}
+	}
+
+	if mapping.WebSocket != nil {
+		for i, m := range mapping.WebSocket.Messages {
+			err = loader.jsonPathCache.AddExpressions(m.Match.JsonPath)
+			if err != nil {
+				return errors.Wrap(err, "error adding mapping from")
+			}
+
+			err = loader.schemaCache.Compile(&mapping.WebSocket.Messages[i].Match, filePath)
+			if err != nil {
+				return errors.Wrap(err, "error adding mapping from")
+			}
+		}
+	}
+
 	mapping.CalcMaxScoreAndCost()
 	mapping.FilePath = filePath
 
 	return nil
 }
+
+func loadResponseBody(response *ResponseMapping, responsesPath string) error {
+	if response.BodyFile == "" {
+		return nil
+	}
+
+	bodyContent, err := loadFile(filepath.Join(responsesPath, response.BodyFile))
+	if err != nil {
+		return errors.Wrap(err, "error loading response body file for mapping")
+	}
+	response.Body = spaceRegex.ReplaceAllString(string(bodyContent), "$1")
+
+	return nil
+}
@@ -12,12 +12,35 @@ import (
 
 var (
 	validLoaderMappings = []Mapping{
+		{
+			Request: RequestMapping{
+				Method: "GET",
+				Host:   CommonMatch{Exact: "service-a.local"},
+				Path:   CommonMatch{Exact: "/health"},
+			},
+			Response: ResponseMapping{StatusCode: 200, Body: `{"service": "a"}`},
+			MaxScore: 2,
+			FilePath: "testdata/load/valid/mapping/@service-a.local/get_health.json",
+		},
+		{
+			Request: RequestMapping{
+				Method: "GET",
+				Host:   CommonMatch{Exact: "service-b.local"},
+				Path:   CommonMatch{Exact: "/health"},
+			},
+			Response: ResponseMapping{StatusCode: 200, Body: `{"service": "b"}`},
+			MaxScore: 2,
+			FilePath: "testdata/load/valid/mapping/@service-b.local/get_health.json",
+		},
 		{
 			Request: RequestMapping{
 				Method: "GET",
 				Path:   CommonMatch{Exact: "/delay/fixed"},
 			},
 			Response: ResponseMapping{StatusCode: 204, ResponseDelay: Delay{Fixed: FixedDelay{Duration: Duration(time.Millisecond * 250)}}},
+			ID:       "fixed-delay",
+			Name:     "Fixed delay",
+			Tags:     []string{"delay"},
 			MaxScore: 1,
 			FilePath: "testdata/load/valid/mapping/get_fixed_delay.json",
 		},
@@ -42,6 +65,40 @@ var (
 			Cost:     20,
 			FilePath: "testdata/load/valid/mapping/get_regex.json",
 		},
+		{
+			Request: RequestMapping{
+				Method: "GET",
+				Path:   CommonMatch{Exact: "/responses"},
+			},
+			Responses: []ResponseMapping{
+				{StatusCode: 200, Body: `{"id": "12345","name": "My Product","description": "This is it"}`, BodyFile: "get_product_12345_response.json"},
+				{StatusCode: 503},
+			},
+			ResponseSelection: ResponseSelection{Mode: SequenceSelection, Loop: true},
+			MaxScore:          1,
+			FilePath:          "testdata/load/valid/mapping/get_responses.json",
+		},
+		{
+			Name: "YAML mapping",
+			Request: RequestMapping{
+				Method: "GET",
+				Path:   CommonMatch{Exact: "/yaml"},
+			},
+			Response: ResponseMapping{StatusCode: 200, Headers: map[string]string{"content-type": "text/plain"}, Body: "From YAML", ResponseDelay: Delay{Fixed: FixedDelay{Duration: Duration(time.Millisecond * 100)}}},
+			MaxScore: 1,
+			FilePath: "testdata/load/valid/mapping/get_yaml.yml",
+		},
+		{
+			Request: RequestMapping{
+				Method: "GET",
+				Path:   CommonMatch{Patterns: []string{"/yaml/[0-9]+"}},
+			},
+			Response: ResponseMapping{StatusCode: 204},
+			MaxScore: 1,
+			Cost:     5,
+			FilePath: "testdata/load/valid/mapping/get_yaml.yml",
+			Index:    1,
+		},
 		{
 			Request: RequestMapping{
 				Method:  "GET",
@@ -63,6 +120,7 @@ var (
 			MaxScore: 2,
 			Cost:     10,
 			FilePath: "testdata/load/valid/mapping/multiple.json",
+			Index:    1,
 		},
 		{
 			Request: RequestMapping{
@@ -126,11 +184,23 @@ var (
 	}
 )
 
+// withGeneratedIDs sets the ID the loader generates on the mappings that don't define one.
+func withGeneratedIDs(mappingsPath string, mappings []Mapping) []Mapping {
+	result := make([]Mapping, len(mappings))
+	for i, m := range mappings {
+		if m.ID == "" {
+			m.ID = GenerateMappingID(relativePath(mappingsPath, m.FilePath), m.Index)
+		}
+		result[i] = m
+	}
+	return result
+}
+
 func TestGetMappings(t *testing.T) {
 	wantMappings := make(Mappings)
-	_ = wantMappings.PutAll(validLoaderMappings)
+	_ = wantMappings.PutAll(withGeneratedIDs("testdata/load/valid/mapping", validLoaderMappings))
 	wantScenarioMappings := make(Mappings)
-	_ = wantScenarioMappings.PutAll(validScenarioMappings)
+	_ = wantScenarioMappings.PutAll(withGeneratedIDs("testdata/load/valid/mapping", validScenarioMappings))
 
 	tests := []struct {
 		name                 string
@@ -200,6 +270,41 @@ func TestDecodeFile(t *testing.T) {
 				Response: ResponseMapping{StatusCode: 200, Headers: map[string]string{"content-type": "application/json"}, BodyFile: "get_product_12345_response.json"},
 			}},
 		},
+		{
+			name: "Should decode YAML file successfully",
+			path: "testdata/decode/get_product_12345.yaml",
+			wantMapping: []Mapping{{
+				Request: RequestMapping{
+					Method:  "GET",
+					Path:    CommonMatch{Exact: "/product/12345"},
+					Headers: map[string]CommonMatch{"accept": {Exact: "application/json"}},
+				},
+				Response: ResponseMapping{StatusCode: 200, Headers: map[string]string{"content-type": "application/json"}, BodyFile: "get_product_12345_response.json"},
+			}},
+		},
+		{
+			name:    "Should return an error with line when decoding an invalid YAML mapping",
+			path:    "testdata/decode/invalid_mapping.yaml",
+			wantErr: errors.New("error decoding file 'testdata/decode/invalid_mapping.yaml': line 6: cannot use string as int in field 'response.statusCode'"),
+		},
+		{
+			name: "Should decode YAML file with non-string keys",
+			path: "testdata/decode/non_string_keys.yaml",
+			wantMapping: []Mapping{{
+				Request:  RequestMapping{Method: "GET", Path: CommonMatch{Exact: "/product/12345"}},
+				Response: ResponseMapping{StatusCode: 200, Headers: map[string]string{"200": "ok", "1.5": "ok"}},
+			}},
+		},
+		{
+			name:    "Should return an error with the field line when a YAML field fails to decode",
+			path:    "testdata/decode/invalid_duration.yaml",
+			wantErr: errors.New(`error decoding file 'testdata/decode/invalid_duration.yaml': line 9: time: invalid duration "soon"`),
+		},
+		{
+			name:    "Should return an error with line when YAML syntax is invalid",
+			path:    "testdata/decode/invalid_syntax.yml",
+			wantErr: errors.New("error decoding file 'testdata/decode/invalid_syntax.yml': yaml: line 3: mapping values are not allowed in this context"),
+		},
 		{
 			name:    "Should return an error if file doesn't exist",
 			path:    "testdata/decode/you_shall_pass.json",
@@ -216,7 +321,7 @@ func TestDecodeFile(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			mapping, err := loader.decodeMapping(tt.path)
+			mapping, _, err := loader.decodeMapping(tt.path)
 
 			if err != nil {
 				if !tt.anyErr {
@@ -233,9 +338,29 @@ func TestDecodeFile(t *testing.T) {
 	}
 }
 
+func TestHostFromPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		filePath string
+		want     string
+	}{
+		{name: "Should return empty host for unscoped folder", filePath: "mapping/users/get.json", want: ""},
+		{name: "Should return host for scoped folder", filePath: "mapping/@users.local/get.json", want: "users.local"},
+		{name: "Should return host for nested scoped folder", filePath: "mapping/@users.local/v1/get.json", want: "users.local"},
+		{name: "Should return innermost host", filePath: "mapping/@users.local/@orders.local/get.json", want: "orders.local"},
+		{name: "Should ignore file name", filePath: "mapping/@get.json", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, hostFromPath("mapping", tt.filePath))
+		})
+	}
+}
+
 func TestLoadMappings(t *testing.T) {
 	wantMappings := make(Mappings)
-	_ = wantMappings.PutAll(validLoaderMappings)
+	_ = wantMappings.PutAll(withGeneratedIDs("testdata/load/valid/mapping", validLoaderMappings))
 
 	tests := []struct {
 		name          string
@@ -256,6 +381,11 @@ func TestLoadMappings(t *testing.T) {
 			mappingsPath: "testdata/load/invalid",
 			wantErr:      `error adding mapping from file [ testdata/load/invalid/invalid_mapping.json ]: mapping definition is invalid: [{"field":"Request.Method","message":"Method is required"},{"field":"Request.Path","message":"Path mapping is required"}]`,
 		},
+		{
+			name:         "Should throw error if mapping ids are duplicated",
+			mappingsPath: "testdata/load/duplicate",
+			wantErr:      "duplicate mapping id 'same-id' in files [ testdata/load/duplicate/get_b.json ] and [ testdata/load/duplicate/get_a.json ]",
+		},
 	}
 
 	loader := NewLoader(NewRegexCache(), NewJSONPathCache(), NewScenarioHandler(nil))
@@ -282,3 +412,12 @@ func TestLoadMappings(t *testing.T) {
 	}
 
 }
+
+func TestGenerateMappingID(t *testing.T) {
+	id := GenerateMappingID("orders/post_order.json", 0)
+
+	assert.Len(t, id, GeneratedIDLength)
+	assert.Equal(t, id, GenerateMappingID("orders/post_order.json", 0))
+	assert.NotEqual(t, id, GenerateMappingID("orders/post_order.json", 1))
+	assert.NotEqual(t, id, GenerateMappingID("orders/get_order.json", 0))
+}
@@ -1,34 +1,81 @@
 package app
 
 import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"net"
+	"path/filepath"
 
 	"github.com/americanas-go/log"
 	"github.com/ohler55/ojg/oj"
+	"github.com/santhosh-tekuri/jsonschema/v5"
 )
 
 const (
+	GeneratedIDLength = 12
+
 	ContainsCost = 2
 	JsonPathCost = 4
 	RegexCost    = 5
+	SchemaCost   = 6
+
+	SchemaMultipleMessage = "Only one of 'schema' or 'schemaFile' can be defined"
+	HostPortMessage       = "Host must not include a port, use 'port' to match it"
 )
 
 type Mapping struct {
-	Scenario *ScenarioMapping `json:"scenario"`
-	Request  RequestMapping   `json:"request"`
-	Response ResponseMapping  `json:"response"`
+	ID                string            `json:"id,omitempty"`
+	Name              string            `json:"name,omitempty"`
+	Tags              []string          `json:"tags,omitempty"`
+	Scenario          *ScenarioMapping  `json:"scenario"`
+	Transitions       []ScenarioTrigger `json:"transitions,omitempty"`
+	Request           RequestMapping    `json:"request"`
+	Response          ResponseMapping   `json:"response"`
+	Responses         []ResponseMapping `json:"responses,omitempty"`
+	ResponseSelection ResponseSelection `json:"responseSelection,omitempty"`
+	Callbacks         []CallbackMapping `json:"callbacks,omitempty"`
+	WebSocket         *WebSocketMapping `json:"webSocket,omitempty"`
+	Contract          *ContractMapping  `json:"contract,omitempty"`
+	Store             *StoreMapping     `json:"store,omitempty"`
 
 	MaxScore int    `json:"-"`
 	Cost     int    `json:"-"`
 	FilePath string `json:"-"`
+	Index    int    `json:"-"`
+}
+
+// Key identifies the mapping by its ID, or by its file and position inside the file when it has none.
+func (m Mapping) Key() string {
+	if m.ID != "" {
+		return m.ID
+	}
+	return fmt.Sprintf("%s#%d", m.FilePath, m.Index)
+}
+
+func (m Mapping) HasTag(tag string) bool {
+	for _, t := range m.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// GenerateMappingID creates a stable ID for a mapping without one, based on
+// the file path relative to the mappings folder and its position inside the file.
+func GenerateMappingID(relativePath string, index int) string {
+	sum := sha1.Sum([]byte(fmt.Sprintf("%s#%d", filepath.ToSlash(relativePath), index)))
+	return hex.EncodeToString(sum[:])[:GeneratedIDLength]
 }
 
 func (m *Mapping) CalcMaxScoreAndCost() {
-	m.MaxScore = m.Request.PathScore() + m.Request.HeaderScore() + m.Request.BodyScore()
+	m.MaxScore = m.Request.HostScore() + m.Request.ClientCertificate.Score() + m.Request.PathScore() + m.Request.HeaderScore() + m.Request.BodyScore()
 
 	var cost int
 
-	cost += m.Request.Path.Cost() + m.Request.Body.Cost()
+	cost += m.Request.Host.Cost() + m.Request.ClientCertificate.Cost() + m.Request.Path.Cost() + m.Request.Body.Cost()
 
 	for _, v := range m.Request.Headers {
 		cost += v.Cost()
@@ -47,10 +94,55 @@ func (m Mapping) Validate() error {
 		errs = append(errs, ValidationError{"Request.Path", "Path mapping is required"})
 	}
 
+	if hasPort(m.Request.Host) {
+		errs = append(errs, ValidationError{"Request.Host", HostPortMessage})
+	}
+
+	if len(m.Request.Body.Schema) > 0 && m.Request.Body.SchemaFile != "" {
+		errs = append(errs, ValidationError{"Request.Body.Schema", SchemaMultipleMessage})
+	}
+
 	if m.Scenario != nil {
 		errs = append(errs, m.Scenario.Validate()...)
 	}
 
+	for _, t := range m.Transitions {
+		errs = append(errs, t.Validate()...)
+	}
+
+	if len(m.Responses) > 0 {
+		if m.Response.StatusCode != 0 {
+			errs = append(errs, ValidationError{"Responses", ResponseMultipleMessage})
+		}
+		errs = append(errs, m.ResponseSelection.Validate(m.Responses)...)
+	}
+
+	if m.Response.Stream != nil {
+		errs = append(errs, m.Response.Stream.Validate("Response.Stream")...)
+	}
+
+	for i, r := range m.Responses {
+		if r.Stream != nil {
+			errs = append(errs, r.Stream.Validate(fmt.Sprintf("Responses[%d].Stream", i))...)
+		}
+	}
+
+	for i, c := range m.Callbacks {
+		errs = append(errs, c.Validate(i)...)
+	}
+
+	if m.WebSocket != nil {
+		errs = append(errs, m.WebSocket.Validate()...)
+	}
+
+	if m.Contract != nil {
+		errs = append(errs, m.Contract.Validate()...)
+	}
+
+	if m.Store != nil {
+		errs = append(errs, m.Store.Validate()...)
+	}
+
 	if len(errs) > 0 {
 		return errs
 	}
@@ -58,6 +150,17 @@ func (m Mapping) Validate() error {
 	return nil
 }
 
+// hasPort reports whether an exact or contains host condition includes a port, which is never part of the
+// host of a request.
+func hasPort(host CommonMatch) bool {
+	for _, v := range append([]string{host.Exact}, host.Contains...) {
+		if _, port, err := net.SplitHostPort(v); err == nil && port != "" {
+			return true
+		}
+	}
+	return false
+}
+
 type Mappings map[string][]Mapping
 
 func (m Mappings) Put(mapping Mapping) error {
@@ -87,6 +190,17 @@ type CommonMatch struct {
 	Patterns []string `json:"pattern,omitempty"`
 }
 
+func (c CommonMatch) IsEmpty() bool {
+	return c.Exact == "" && len(c.Contains) == 0 && len(c.Patterns) == 0
+}
+
+func (c CommonMatch) Score() int {
+	if c.Exact != "" {
+		return 1
+	}
+	return len(c.Contains) + len(c.Patterns)
+}
+
 func (c CommonMatch) Cost() int {
 	return (len(c.Contains) * ContainsCost) + (len(c.Patterns) * RegexCost)
 }
@@ -94,21 +208,55 @@ func (c CommonMatch) Cost() int {
 type BodyMatch struct {
 	CommonMatch
 	JsonPath []string `json:"jsonPath,omitempty"`
+
+	// Schema is a JSON Schema the body must be valid against. SchemaFile is used instead to read it
+	// from a file, relative to the folder of the mapping file.
+	Schema     json.RawMessage `json:"schema,omitempty"`
+	SchemaFile string          `json:"schemaFile,omitempty"`
+
+	schema *jsonschema.Schema
+}
+
+func (b BodyMatch) HasSchema() bool {
+	return len(b.Schema) > 0 || b.SchemaFile != ""
 }
 
 func (b BodyMatch) Cost() int {
-	return (len(b.Contains) * ContainsCost) + (len(b.Patterns) * RegexCost) + (len(b.JsonPath) * JsonPathCost)
+	cost := (len(b.Contains) * ContainsCost) + (len(b.Patterns) * RegexCost) + (len(b.JsonPath) * JsonPathCost)
+	if b.HasSchema() {
+		cost += SchemaCost
+	}
+	return cost
 }
 
 type RequestMapping struct {
-	Method  string                 `json:"method"`
-	Path    CommonMatch            `json:"path"`
-	Headers map[string]CommonMatch `json:"headers,omitempty"`
-	Body    BodyMatch              `json:"body,omitempty"`
+	Method            string                 `json:"method"`
+	Scheme            string                 `json:"scheme,omitempty"`
+	Host              CommonMatch            `json:"host,omitempty"`
+	Port              string                 `json:"port,omitempty"`
+	ClientCertificate CommonMatch            `json:"clientCertificate,omitempty"`
+	Path              CommonMatch            `json:"path"`
+	Headers           map[string]CommonMatch `json:"headers,omitempty"`
+	Body              BodyMatch              `json:"body,omitempty"`
 }
 
 func (m RequestMapping) HasPath() bool {
-	return m.Path.Exact != "" || len(m.Path.Contains) > 0 || len(m.Path.Patterns) > 0
+	return !m.Path.IsEmpty()
+}
+
+func (m RequestMapping) HasHost() bool {
+	return !m.Host.IsEmpty()
+}
+
+func (m RequestMapping) HostScore() int {
+	var score int
+	if m.Scheme != "" {
+		score++
+	}
+	if m.Port != "" {
+		score++
+	}
+	return score + m.Host.Score()
 }
 
 func (m RequestMapping) HeaderScore() int {
@@ -135,7 +283,11 @@ func (m RequestMapping) BodyScore() int {
 	if m.Body.Exact != "" {
 		return 1
 	}
-	return len(m.Body.JsonPath) + len(m.Body.Contains) + len(m.Body.Patterns)
+	score := len(m.Body.JsonPath) + len(m.Body.Contains) + len(m.Body.Patterns)
+	if m.Body.HasSchema() {
+		score++
+	}
+	return score
 }
 
 type ScenarioMapping struct {
@@ -143,6 +295,10 @@ type ScenarioMapping struct {
 	StartingState bool   `json:"startingState"`
 	State         string `json:"state"`
 	NewState      string `json:"newState"`
+
+	Isolation   *ScenarioIsolation `json:"isolation,omitempty"`
+	Expiry      *ScenarioExpiry    `json:"expiry,omitempty"`
+	StateExpiry *ScenarioExpiry    `json:"stateExpiry,omitempty"`
 }
 
 func (s *ScenarioMapping) Validate() ValidationErrors {
@@ -153,6 +309,15 @@ func (s *ScenarioMapping) Validate() ValidationErrors {
 	if s.State == "" {
 		errs = append(errs, ValidationError{"Scenario.State", "Scenario state is required"})
 	}
+	if s.Isolation != nil {
+		errs = append(errs, s.Isolation.Validate()...)
+	}
+	if s.Expiry != nil {
+		errs = append(errs, s.Expiry.Validate("Scenario.Expiry")...)
+	}
+	if s.StateExpiry != nil {
+		errs = append(errs, s.StateExpiry.Validate("Scenario.StateExpiry")...)
+	}
 
 	return errs
 }
@@ -163,6 +328,17 @@ type ResponseMapping struct {
 	BodyFile      string            `json:"bodyFile,omitempty"`
 	Body          string            `json:"body,omitempty"`
 	ResponseDelay Delay             `json:"delay,omitempty"`
+	Weight        *int              `json:"weight,omitempty"`
+	Stream        *EventStream      `json:"stream,omitempty"`
+}
+
+// EffectiveWeight returns the weight of the response for random selection, defaulting to 1.
+// A weight of 0 switches the response off.
+func (r ResponseMapping) EffectiveWeight() int {
+	if r.Weight == nil {
+		return 1
+	}
+	return *r.Weight
 }
 
 type ValidationError struct {
@@ -7,15 +7,22 @@ import (
 type Matcher struct {
 	regexCache    *RegexCache
 	jsonPathCache *JSONPathCache
+	toggles       *MappingToggles
 }
 
 func NewMatcher(r *RegexCache, j *JSONPathCache) *Matcher {
 	return &Matcher{
 		regexCache:    r,
 		jsonPathCache: j,
+		toggles:       NewMappingToggles(),
 	}
 }
 
+// Toggles returns the mappings disabled at runtime, which are skipped when matching.
+func (matcher *Matcher) Toggles() *MappingToggles {
+	return matcher.toggles
+}
+
 func (matcher *Matcher) Match(r Request, mappings Mappings, scenarioStates map[string]ScenarioState) (Mapping, bool, bool) {
 	methodMappings, ok := mappings[r.Method]
 	if !ok {
@@ -25,8 +32,28 @@ func (matcher *Matcher) Match(r Request, mappings Mappings, scenarioStates map[s
 	bestIndex, bestScore := -1, 0
 
 	for i, mapping := range methodMappings {
+		if !matcher.toggles.Enabled(&mapping) {
+			continue
+		}
+
 		var score int
 
+		if matcher.matchScheme(r, mapping) && mapping.Request.Scheme != "" {
+			score++
+		}
+
+		if matcher.matchPort(r, mapping) && mapping.Request.Port != "" {
+			score++
+		}
+
+		if matcher.matchHost(r, mapping) {
+			score += mapping.Request.Host.Score()
+		}
+
+		if matcher.matchClientCertificate(r, mapping) {
+			score += mapping.Request.ClientCertificate.Score()
+		}
+
 		if matcher.matchPath(r, mapping) {
 			score += mapping.Request.PathScore()
 		}
@@ -62,6 +89,47 @@ func (matcher *Matcher) Match(r Request, mappings Mappings, scenarioStates map[s
 	return Mapping{}, false, false
 }
 
+func (matcher *Matcher) matchScheme(r Request, m Mapping) bool {
+	return m.Request.Scheme == "" || strings.EqualFold(r.Scheme, m.Request.Scheme)
+}
+
+func (matcher *Matcher) matchPort(r Request, m Mapping) bool {
+	return m.Request.Port == "" || r.Port == m.Request.Port
+}
+
+// matchHost checks the host ignoring case, as host names are case insensitive.
+func (matcher *Matcher) matchHost(r Request, m Mapping) bool {
+	host := strings.ToLower(r.Host)
+	if m.Request.Host.Exact != "" {
+		return host == strings.ToLower(m.Request.Host.Exact)
+	}
+
+	for _, c := range m.Request.Host.Contains {
+		if !strings.Contains(host, strings.ToLower(c)) {
+			return false
+		}
+	}
+
+	for _, p := range m.Request.Host.Patterns {
+		if !matcher.regexCache.Match(hostPattern(p), host) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// hostPattern makes a host pattern case insensitive. The pattern itself isn't lowercased, which would
+// change escapes like \D or \W.
+func hostPattern(p string) string {
+	return "(?i)" + p
+}
+
+// matchClientCertificate checks the common name of the certificate presented by the client.
+func (matcher *Matcher) matchClientCertificate(r Request, m Mapping) bool {
+	return matcher.MatchBody(BodyMatch{CommonMatch: m.Request.ClientCertificate}, r.ClientCertificate)
+}
+
 func (matcher *Matcher) matchPath(r Request, m Mapping) bool {
 	if m.Request.Path.Exact != "" {
 		return r.Path == m.Request.Path.Exact
@@ -113,27 +181,36 @@ func (matcher *Matcher) matchHeaders(r Request, m Mapping) bool {
 }
 
 func (matcher *Matcher) matchBody(r Request, m Mapping) bool {
-	if m.Request.Body.Exact != "" {
-		return r.Body == m.Request.Body.Exact
+	return matcher.MatchBody(m.Request.Body, r.Body)
+}
+
+// MatchBody checks if the value matches all conditions of the body mapping.
+func (matcher *Matcher) MatchBody(b BodyMatch, value string) bool {
+	if b.Exact != "" {
+		return value == b.Exact
 	}
 
-	for _, c := range m.Request.Body.Contains {
-		if !strings.Contains(r.Body, c) {
+	for _, c := range b.Contains {
+		if !strings.Contains(value, c) {
 			return false
 		}
 	}
 
-	for _, p := range m.Request.Body.Patterns {
-		if !matcher.regexCache.Match(p, r.Body) {
+	for _, p := range b.Patterns {
+		if !matcher.regexCache.Match(p, value) {
 			return false
 		}
 	}
 
-	if len(m.Request.Body.JsonPath) > 0 {
-		if !matcher.jsonPathCache.Match(m.Request.Body.JsonPath, r.Body) {
+	if len(b.JsonPath) > 0 {
+		if !matcher.jsonPathCache.Match(b.JsonPath, value) {
 			return false
 		}
 	}
 
+	if len(SchemaViolations(b, value)) > 0 {
+		return false
+	}
+
 	return true
 }
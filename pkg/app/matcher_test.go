@@ -86,6 +86,74 @@ func TestMatcher(t *testing.T) {
 			want:      MatchResult{StatusCode: 201, Matched: true, Headers: map[string]string{"location": "999", "X-Mapping-File": "file_11"}},
 			wantMatch: true,
 		},
+		{
+			name:      "Should match GET request by exact host",
+			input:     Request{Method: "GET", Host: "users.local", Port: "80", Path: "/health"},
+			want:      MatchResult{StatusCode: 200, Matched: true, Headers: map[string]string{"X-Mapping-File": "file_15"}, Body: "users"},
+			wantMatch: true,
+		},
+		{
+			name:      "Should match GET request by host pattern, scheme and port",
+			input:     Request{Method: "GET", Scheme: "https", Host: "orders.local", Port: "8443", Path: "/health"},
+			want:      MatchResult{StatusCode: 200, Matched: true, Headers: map[string]string{"X-Mapping-File": "file_16"}, Body: "orders"},
+			wantMatch: true,
+		},
+		{
+			name:      "Should match GET request by host pattern ignoring case",
+			input:     Request{Method: "GET", Scheme: "https", Host: "Orders.Local", Port: "8443", Path: "/health"},
+			want:      MatchResult{StatusCode: 200, Matched: true, Headers: map[string]string{"X-Mapping-File": "file_16"}, Body: "orders"},
+			wantMatch: true,
+		},
+		{
+			name:        "Should not match GET request if port does not match",
+			input:       Request{Method: "GET", Scheme: "https", Host: "orders.local", Port: "443", Path: "/health"},
+			wantMatch:   false,
+			wantPartial: true,
+			want: MatchResult{
+				Matched:    false,
+				StatusCode: 404,
+				Headers:    map[string]string{"Content-type": "application/json", "X-Mapping-File": "file_16"},
+				Body: NotFoundResponse{
+					Message: NoMappingFoundMessage,
+					Request: Request{Method: "GET", Scheme: "https", Host: "orders.local", Port: "443", Path: "/health"},
+					ClosestMapping: &RequestMapping{
+						Method: "GET",
+						Scheme: "https",
+						Host:   CommonMatch{Patterns: []string{`^orders\.`}},
+						Port:   "8443",
+						Path:   CommonMatch{Exact: "/health"},
+					},
+					Candidates: []MatchDiff{},
+				},
+			},
+		},
+		{
+			name:      "Should match GET request by client certificate",
+			input:     Request{Method: "GET", Scheme: "https", ClientCertificate: "partner-a", Path: "/partner/orders"},
+			want:      MatchResult{StatusCode: 200, Matched: true, Headers: map[string]string{"X-Mapping-File": "file_17"}, Body: "partner orders"},
+			wantMatch: true,
+		},
+		{
+			name:        "Should not match GET request without the client certificate",
+			input:       Request{Method: "GET", Path: "/partner/orders"},
+			wantMatch:   false,
+			wantPartial: true,
+			want: MatchResult{
+				Matched:    false,
+				StatusCode: 404,
+				Headers:    map[string]string{"Content-type": "application/json", "X-Mapping-File": "file_17"},
+				Body: NotFoundResponse{
+					Message: NoMappingFoundMessage,
+					Request: Request{Method: "GET", Path: "/partner/orders"},
+					ClosestMapping: &RequestMapping{
+						Method:            "GET",
+						ClientCertificate: CommonMatch{Exact: "partner-a"},
+						Path:              CommonMatch{Exact: "/partner/orders"},
+					},
+					Candidates: []MatchDiff{},
+				},
+			},
+		},
 		{
 			name:      "Should not match GET request if path does not match regex",
 			input:     Request{Method: "GET", Path: "/regex/abc"},
@@ -124,6 +192,7 @@ func TestMatcher(t *testing.T) {
 					Message:        NoMappingFoundMessage,
 					Request:        Request{Method: "GET", Path: "/nomatchhere"},
 					ClosestMapping: nil,
+					Candidates:     []MatchDiff{},
 				},
 			},
 		},
@@ -144,6 +213,7 @@ func TestMatcher(t *testing.T) {
 						Path:    CommonMatch{Exact: "/bears/321"},
 						Headers: map[string]CommonMatch{"authorization": {Exact: "Bearer Bear 🐻"}},
 					},
+					Candidates: []MatchDiff{},
 				},
 			},
 		},
@@ -308,9 +378,44 @@ func getMappings() Mappings {
 			Cost:     0,
 			FilePath: "file_14",
 		},
+		{
+			Request:  RequestMapping{Method: "GET", Host: CommonMatch{Exact: "users.local"}, Path: CommonMatch{Exact: "/health"}},
+			Response: ResponseMapping{StatusCode: 200, Body: "users"},
+			MaxScore: 2,
+			Cost:     0,
+			FilePath: "file_15",
+		},
+		{
+			Request:  RequestMapping{Method: "GET", Scheme: "https", Host: CommonMatch{Patterns: []string{`^orders\.`}}, Port: "8443", Path: CommonMatch{Exact: "/health"}},
+			Response: ResponseMapping{StatusCode: 200, Body: "orders"},
+			MaxScore: 4,
+			Cost:     5,
+			FilePath: "file_16",
+		},
+		{
+			Request:  RequestMapping{Method: "GET", ClientCertificate: CommonMatch{Exact: "partner-a"}, Path: CommonMatch{Exact: "/partner/orders"}},
+			Response: ResponseMapping{StatusCode: 200, Body: "partner orders"},
+			MaxScore: 2,
+			Cost:     0,
+			FilePath: "file_17",
+		},
 	}
 
 	ms := make(Mappings)
 	_ = ms.PutAll(mappings)
 	return ms
 }
+
+func TestValidateHost(t *testing.T) {
+	mapping := Mapping{Request: RequestMapping{Method: "GET", Path: CommonMatch{Exact: "/health"}}}
+
+	for _, host := range []CommonMatch{{Exact: "users.local"}, {Exact: "::1"}, {Contains: []string{"users"}}, {Patterns: []string{`:8080$`}}} {
+		mapping.Request.Host = host
+		require.NoError(t, mapping.Validate())
+	}
+
+	for _, host := range []CommonMatch{{Exact: "users.local:8080"}, {Exact: "[::1]:8080"}, {Contains: []string{"users.local:8080"}}} {
+		mapping.Request.Host = host
+		require.Equal(t, ValidationErrors{{"Request.Host", HostPortMessage}}, mapping.Validate())
+	}
+}
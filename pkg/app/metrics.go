@@ -0,0 +1,109 @@
+package app
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const metricsNamespace = "mantis"
+
+// Metrics holds the Prometheus collectors describing how Mantis is being used.
+type Metrics struct {
+	registry            *prometheus.Registry
+	requests            *prometheus.CounterVec
+	unmatched           prometheus.Counter
+	scenarioTransitions *prometheus.CounterVec
+	matchDuration       prometheus.Histogram
+	delayDuration       prometheus.Histogram
+	mappings            *prometheus.GaugeVec
+}
+
+func NewMetrics(mappings Mappings, scenarioHandler *ScenarioHandler) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "requests_total",
+			Help:      "Requests received, by method, match outcome and mapping file.",
+		}, []string{"method", "matched", "mapping_file"}),
+		unmatched: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "unmatched_requests_total",
+			Help:      "Requests that did not match any mapping.",
+		}),
+		scenarioTransitions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "scenario_transitions_total",
+			Help:      "Scenario state transitions, by scenario and states.",
+		}, []string{"scenario", "from", "to"}),
+		matchDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "match_duration_seconds",
+			Help:      "Time spent matching requests against the mappings.",
+			Buckets:   []float64{.00001, .000025, .00005, .0001, .00025, .0005, .001, .0025, .005, .01, .025},
+		}),
+		delayDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "response_delay_seconds",
+			Help:      "Delay applied to responses before they are sent.",
+			Buckets:   prometheus.DefBuckets,
+		}),
+		mappings: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "mappings",
+			Help:      "Loaded mappings, by method.",
+		}, []string{"method"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests,
+		m.unmatched,
+		m.scenarioTransitions,
+		m.matchDuration,
+		m.delayDuration,
+		m.mappings,
+	)
+
+	m.setMappings(mappings)
+	if scenarioHandler != nil {
+		m.setMappings(scenarioHandler.scenarioMappings)
+	}
+
+	return m
+}
+
+func (m *Metrics) setMappings(mappings Mappings) {
+	for method, methodMappings := range mappings {
+		m.mappings.WithLabelValues(method).Add(float64(len(methodMappings)))
+	}
+}
+
+// ObserveRequest records the outcome of a request and the time spent matching it.
+func (m *Metrics) ObserveRequest(r Request, matched bool, mappingFile string, matchDuration time.Duration) {
+	m.requests.WithLabelValues(r.Method, strconv.FormatBool(matched), mappingFile).Inc()
+	if !matched {
+		m.unmatched.Inc()
+	}
+	m.matchDuration.Observe(matchDuration.Seconds())
+}
+
+func (m *Metrics) ObserveDelay(d time.Duration) {
+	m.delayDuration.Observe(d.Seconds())
+}
+
+func (m *Metrics) ObserveScenarioTransition(scenario *ScenarioMapping) {
+	m.scenarioTransitions.WithLabelValues(scenario.Name, scenario.State, scenario.NewState).Inc()
+}
+
+// Handler exposes the metrics in the Prometheus text format.
+func (m *Metrics) Handler() fiber.Handler {
+	return adaptor.HTTPHandler(promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{}))
+}
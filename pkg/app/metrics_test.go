@@ -0,0 +1,80 @@
+package app
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetrics(t *testing.T) {
+	mappings := Mappings{
+		"GET": []Mapping{
+			{
+				Request:  RequestMapping{Method: "GET", Path: CommonMatch{Exact: "/users"}},
+				Response: ResponseMapping{StatusCode: 200},
+				MaxScore: 1,
+				FilePath: "users.json",
+			},
+			{
+				Request:  RequestMapping{Method: "GET", Path: CommonMatch{Exact: "/orders"}, Headers: map[string]CommonMatch{"x-tenant": {Exact: "acme"}}},
+				Response: ResponseMapping{StatusCode: 200},
+				MaxScore: 2,
+				FilePath: "orders.json",
+			},
+		},
+	}
+
+	matcher := NewMatcher(NewRegexCache(), NewJSONPathCache())
+	scenarioHandler := NewScenarioHandler(matcher)
+	scenarioHandler.AddScenario(Mapping{
+		Scenario: &ScenarioMapping{Name: "cart", StartingState: true, State: "empty", NewState: "full"},
+		Request:  RequestMapping{Method: "POST", Path: CommonMatch{Exact: "/cart"}},
+		Response: ResponseMapping{StatusCode: 201},
+		MaxScore: 1,
+		FilePath: "cart_empty.json",
+	})
+	scenarioHandler.AddScenario(Mapping{
+		Scenario: &ScenarioMapping{Name: "cart", State: "full"},
+		Request:  RequestMapping{Method: "POST", Path: CommonMatch{Exact: "/cart"}},
+		Response: ResponseMapping{StatusCode: 409},
+		MaxScore: 1,
+		FilePath: "cart_full.json",
+	})
+
+	metrics := NewMetrics(mappings, scenarioHandler)
+	service := NewService(mappings, matcher, scenarioHandler, &mockDelayer{}, NewResponseSelector(), NewCallbackDispatcher(&mockDelayer{}), NewStore(), NewJournal(), metrics, NewNoopTracing())
+
+	service.MatchRequest(context.Background(), Request{Method: "GET", Path: "/users"})
+	service.MatchRequest(context.Background(), Request{Method: "GET", Path: "/users"})
+	service.MatchRequest(context.Background(), Request{Method: "GET", Path: "/orders"})
+	service.MatchRequest(context.Background(), Request{Method: "GET", Path: "/products"})
+	service.MatchRequest(context.Background(), Request{Method: "POST", Path: "/cart"})
+
+	app := fiber.New()
+	app.Get("/metrics", metrics.Handler())
+
+	res, err := app.Test(httptest.NewRequest("GET", "/metrics", nil))
+	require.NoError(t, err)
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+
+	for _, line := range []string{
+		`mantis_requests_total{mapping_file="users.json",matched="true",method="GET"} 2`,
+		`mantis_requests_total{mapping_file="",matched="false",method="GET"} 2`,
+		`mantis_requests_total{mapping_file="cart_empty.json",matched="true",method="POST"} 1`,
+		`mantis_unmatched_requests_total 2`,
+		`mantis_scenario_transitions_total{from="empty",scenario="cart",to="full"} 1`,
+		`mantis_match_duration_seconds_count 5`,
+		`mantis_response_delay_seconds_count 3`,
+		`mantis_mappings{method="GET"} 2`,
+		`mantis_mappings{method="POST"} 2`,
+	} {
+		assert.Contains(t, string(body), line)
+	}
+	assert.NotContains(t, string(body), `mapping_file="orders.json"`)
+}
@@ -0,0 +1,515 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// OpenAPIMaxSchemaDepth limits how deep nested schemas are followed when synthesizing a response body.
+	OpenAPIMaxSchemaDepth = 8
+
+	openAPIAnyValuePattern = ".+"
+)
+
+var (
+	openAPIPathParamRegex = regexp.MustCompile(`\{[^/}]+\}`)
+
+	// openAPIIgnoredHeaders are described by other parts of the operation and are not matched as header parameters.
+	openAPIIgnoredHeaders = map[string]bool{"accept": true, "content-type": true, "authorization": true}
+)
+
+type openAPIDocument struct {
+	OpenAPI    string                     `json:"openapi"`
+	Info       openAPIInfo                `json:"info"`
+	Paths      map[string]openAPIPathItem `json:"paths"`
+	Components openAPIComponents          `json:"components"`
+}
+
+type openAPIInfo struct {
+	Title string `json:"title"`
+}
+
+type openAPIComponents struct {
+	Schemas    map[string]*openAPISchema   `json:"schemas"`
+	Parameters map[string]openAPIParameter `json:"parameters"`
+	Responses  map[string]openAPIResponse  `json:"responses"`
+	Examples   map[string]openAPIExample   `json:"examples"`
+}
+
+type openAPIPathItem struct {
+	Parameters []openAPIParameter `json:"parameters"`
+	Get        *openAPIOperation  `json:"get"`
+	Put        *openAPIOperation  `json:"put"`
+	Post       *openAPIOperation  `json:"post"`
+	Delete     *openAPIOperation  `json:"delete"`
+	Options    *openAPIOperation  `json:"options"`
+	Head       *openAPIOperation  `json:"head"`
+	Patch      *openAPIOperation  `json:"patch"`
+	Trace      *openAPIOperation  `json:"trace"`
+}
+
+func (p openAPIPathItem) operations() map[string]*openAPIOperation {
+	return map[string]*openAPIOperation{
+		http.MethodGet:     p.Get,
+		http.MethodPut:     p.Put,
+		http.MethodPost:    p.Post,
+		http.MethodDelete:  p.Delete,
+		http.MethodOptions: p.Options,
+		http.MethodHead:    p.Head,
+		http.MethodPatch:   p.Patch,
+		http.MethodTrace:   p.Trace,
+	}
+}
+
+type openAPIOperation struct {
+	OperationID string                     `json:"operationId"`
+	Summary     string                     `json:"summary"`
+	Tags        []string                   `json:"tags"`
+	Parameters  []openAPIParameter         `json:"parameters"`
+	Responses   map[string]openAPIResponse `json:"responses"`
+}
+
+type openAPIParameter struct {
+	Ref      string `json:"$ref"`
+	Name     string `json:"name"`
+	In       string `json:"in"`
+	Required bool   `json:"required"`
+}
+
+type openAPIResponse struct {
+	Ref     string                      `json:"$ref"`
+	Content map[string]openAPIMediaType `json:"content"`
+}
+
+type openAPIMediaType struct {
+	Schema   *openAPISchema            `json:"schema"`
+	Example  any                       `json:"example"`
+	Examples map[string]openAPIExample `json:"examples"`
+}
+
+type openAPIExample struct {
+	Ref   string `json:"$ref"`
+	Value any    `json:"value"`
+}
+
+type openAPISchema struct {
+	Ref        string                    `json:"$ref"`
+	Type       any                       `json:"type"`
+	Format     string                    `json:"format"`
+	Properties map[string]*openAPISchema `json:"properties"`
+	Items      *openAPISchema            `json:"items"`
+	AllOf      []*openAPISchema          `json:"allOf"`
+	OneOf      []*openAPISchema          `json:"oneOf"`
+	AnyOf      []*openAPISchema          `json:"anyOf"`
+	Enum       []any                     `json:"enum"`
+	Example    any                       `json:"example"`
+	Default    any                       `json:"default"`
+}
+
+// schemaType returns the type of the schema, the first non null one when a list of types is given.
+func (s *openAPISchema) schemaType() string {
+	switch t := s.Type.(type) {
+	case string:
+		return t
+	case []any:
+		for _, v := range t {
+			if name, ok := v.(string); ok && name != "null" {
+				return name
+			}
+		}
+	}
+
+	if len(s.Properties) > 0 {
+		return "object"
+	}
+	return ""
+}
+
+// ImportOpenAPI generates a mapping for each operation of an OpenAPI 3 document, in JSON or YAML.
+//
+// The request matches the method, the path template and the required query and header parameters. The response
+// uses the first success status of the operation, with the body taken from its examples or synthesized from its schema.
+// The import is named after the document title when no name is given, a given name may only contain lowercase
+// letters, digits, '-' and '_'.
+func ImportOpenAPI(content []byte, name string) (Import, error) {
+	content, err := YAMLToJSON(content)
+	if err != nil {
+		return Import{}, errors.Wrap(err, "error decoding OpenAPI document")
+	}
+
+	var doc openAPIDocument
+	if err := json.Unmarshal(content, &doc); err != nil {
+		return Import{}, errors.Wrap(err, "error decoding OpenAPI document")
+	}
+
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		return Import{}, errors.Errorf("unsupported OpenAPI version '%s', only 3.x documents are supported", doc.OpenAPI)
+	}
+
+	if name != "" {
+		if err := validateImportName(name); err != nil {
+			return Import{}, err
+		}
+	}
+	if name == "" {
+		name = Slug(doc.Info.Title)
+	}
+	if name == "" {
+		name = "openapi"
+	}
+
+	imp := Import{Name: name, Bodies: map[string]string{}}
+
+	// the files are named after the slug of the IDs, so IDs like 'getPet' and 'GetPet' get a suffix to keep them apart
+	used := make(map[string]bool)
+	uniqueID := func(base string) string {
+		id := base
+		for n := 2; used[Slug(id)]; n++ {
+			id = fmt.Sprintf("%s_%d", base, n)
+		}
+		used[Slug(id)] = true
+		return id
+	}
+
+	paths := make([]string, 0, len(doc.Paths))
+	for p := range doc.Paths {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	for _, p := range paths {
+		item := doc.Paths[p]
+		operations := item.operations()
+
+		methods := make([]string, 0, len(operations))
+		for method, op := range operations {
+			if op != nil {
+				methods = append(methods, method)
+			}
+		}
+		sort.Strings(methods)
+
+		for _, method := range methods {
+			op := operations[method]
+			id := op.OperationID
+			if id == "" {
+				id = Slug(method + " " + p)
+			}
+
+			m, err := doc.mapping(uniqueID(id), method, p, item, op, &imp)
+			if err != nil {
+				return Import{}, errors.Wrapf(err, "error importing operation '%s %s'", method, p)
+			}
+			imp.Mappings = append(imp.Mappings, m)
+		}
+	}
+
+	return imp, nil
+}
+
+func (doc *openAPIDocument) mapping(id, method, p string, item openAPIPathItem, op *openAPIOperation, imp *Import) (Mapping, error) {
+	m := Mapping{
+		ID:      importID(imp.Name, id),
+		Name:    op.Summary,
+		Tags:    op.Tags,
+		Request: RequestMapping{Method: method},
+	}
+
+	params, err := doc.parameters(item.Parameters, op.Parameters)
+	if err != nil {
+		return m, err
+	}
+
+	hasQuery := false
+	var queryPatterns []string
+	for _, param := range params {
+		switch param.In {
+		case "query":
+			hasQuery = true
+			if param.Required {
+				queryPatterns = append(queryPatterns, `[?&]`+regexp.QuoteMeta(param.Name)+`=`)
+			}
+		case "header":
+			name := strings.ToLower(param.Name)
+			if !param.Required || openAPIIgnoredHeaders[name] {
+				continue
+			}
+			if m.Request.Headers == nil {
+				m.Request.Headers = map[string]CommonMatch{}
+			}
+			m.Request.Headers[name] = CommonMatch{Patterns: []string{openAPIAnyValuePattern}}
+		}
+	}
+
+	if !hasQuery && !openAPIPathParamRegex.MatchString(p) {
+		m.Request.Path = CommonMatch{Exact: p}
+	} else {
+		m.Request.Path = CommonMatch{Patterns: append([]string{openAPIPathPattern(p)}, queryPatterns...)}
+	}
+
+	m.Response, err = doc.response(op.Responses, path.Join(imp.Name, Slug(id)), imp)
+	return m, err
+}
+
+// openAPIPathPattern converts a path template to a regex matching any value for its parameters and any query string.
+func openAPIPathPattern(p string) string {
+	var sb strings.Builder
+	sb.WriteString("^")
+
+	last := 0
+	for _, loc := range openAPIPathParamRegex.FindAllStringIndex(p, -1) {
+		sb.WriteString(regexp.QuoteMeta(p[last:loc[0]]))
+		sb.WriteString(`[^/?]+`)
+		last = loc[1]
+	}
+	sb.WriteString(regexp.QuoteMeta(p[last:]))
+	sb.WriteString(`(\?.*)?$`)
+
+	return sb.String()
+}
+
+// parameters resolves the parameters of the path and the operation, the ones of the operation take precedence.
+func (doc *openAPIDocument) parameters(pathParams, opParams []openAPIParameter) ([]openAPIParameter, error) {
+	resolved := make([]openAPIParameter, 0, len(pathParams)+len(opParams))
+	index := map[string]int{}
+
+	for _, param := range append(append([]openAPIParameter{}, pathParams...), opParams...) {
+		if param.Ref != "" {
+			ref, ok := doc.Components.Parameters[refName(param.Ref, "parameters")]
+			if !ok {
+				return nil, errors.Errorf("parameter reference '%s' not found", param.Ref)
+			}
+			param = ref
+		}
+
+		key := param.In + ":" + param.Name
+		if i, ok := index[key]; ok {
+			resolved[i] = param
+			continue
+		}
+		index[key] = len(resolved)
+		resolved = append(resolved, param)
+	}
+
+	return resolved, nil
+}
+
+// response builds the response of the first success status, adding its body to the import as a body file.
+func (doc *openAPIDocument) response(responses map[string]openAPIResponse, bodyFile string, imp *Import) (ResponseMapping, error) {
+	status, res := openAPIResponseStatus(responses)
+	if res == nil {
+		return ResponseMapping{StatusCode: status}, nil
+	}
+
+	if res.Ref != "" {
+		ref, ok := doc.Components.Responses[refName(res.Ref, "responses")]
+		if !ok {
+			return ResponseMapping{}, errors.Errorf("response reference '%s' not found", res.Ref)
+		}
+		res = &ref
+	}
+
+	response := ResponseMapping{StatusCode: status}
+	if len(res.Content) == 0 || status == http.StatusNoContent {
+		return response, nil
+	}
+
+	mediaType := openAPIMediaTypeName(res.Content)
+	media := res.Content[mediaType]
+
+	value, ok := doc.example(media)
+	if !ok {
+		return response, nil
+	}
+
+	var body string
+	if s, isString := value.(string); isString && !strings.Contains(mediaType, "json") {
+		body = s
+	} else {
+		content, err := marshalIndent(value)
+		if err != nil {
+			return response, errors.Wrap(err, "error encoding response example")
+		}
+		body = string(content)
+	}
+
+	ext := ".txt"
+	if strings.Contains(mediaType, "json") {
+		ext = ".json"
+	}
+
+	response.Headers = map[string]string{"Content-Type": mediaType}
+	response.BodyFile = bodyFile + "_response" + ext
+	imp.Bodies[response.BodyFile] = body
+
+	return response, nil
+}
+
+// openAPIResponseStatus picks the lowest success status, falling back to 'default' as a 200 or the lowest status defined.
+func openAPIResponseStatus(responses map[string]openAPIResponse) (int, *openAPIResponse) {
+	var codes []string
+	for code := range responses {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	pick := func(code string) (int, *openAPIResponse) {
+		res := responses[code]
+		status, err := strconv.Atoi(strings.ReplaceAll(strings.ToUpper(code), "X", "0"))
+		if err != nil {
+			status = http.StatusOK
+		}
+		return status, &res
+	}
+
+	for _, code := range codes {
+		if strings.HasPrefix(code, "2") {
+			return pick(code)
+		}
+	}
+	if _, ok := responses["default"]; ok {
+		return pick("default")
+	}
+	if len(codes) > 0 {
+		return pick(codes[0])
+	}
+
+	return http.StatusOK, nil
+}
+
+// openAPIMediaTypeName prefers a JSON media type, falling back to the first one in alphabetical order.
+func openAPIMediaTypeName(content map[string]openAPIMediaType) string {
+	names := make([]string, 0, len(content))
+	for name := range content {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if strings.Contains(name, "json") {
+			return name
+		}
+	}
+	return names[0]
+}
+
+// example returns the example of the media type, the first of its named examples or one synthesized from its schema.
+func (doc *openAPIDocument) example(media openAPIMediaType) (any, bool) {
+	if media.Example != nil {
+		return media.Example, true
+	}
+
+	names := make([]string, 0, len(media.Examples))
+	for name := range media.Examples {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		ex := media.Examples[name]
+		if ex.Ref != "" {
+			ex = doc.Components.Examples[refName(ex.Ref, "examples")]
+		}
+		if ex.Value != nil {
+			return ex.Value, true
+		}
+	}
+
+	if media.Schema == nil {
+		return nil, false
+	}
+
+	return doc.synthesize(media.Schema, 0), true
+}
+
+// synthesize builds a value that conforms to the schema, using its examples, defaults and enums when available.
+func (doc *openAPIDocument) synthesize(s *openAPISchema, depth int) any {
+	if s == nil || depth > OpenAPIMaxSchemaDepth {
+		return nil
+	}
+
+	if s.Ref != "" {
+		return doc.synthesize(doc.Components.Schemas[refName(s.Ref, "schemas")], depth+1)
+	}
+
+	if s.Example != nil {
+		return s.Example
+	}
+	if s.Default != nil {
+		return s.Default
+	}
+	if len(s.Enum) > 0 {
+		return s.Enum[0]
+	}
+
+	if len(s.AllOf) > 0 {
+		merged := map[string]any{}
+		for _, sub := range s.AllOf {
+			if obj, ok := doc.synthesize(sub, depth+1).(map[string]any); ok {
+				for k, v := range obj {
+					merged[k] = v
+				}
+			}
+		}
+		return merged
+	}
+	if len(s.OneOf) > 0 {
+		return doc.synthesize(s.OneOf[0], depth+1)
+	}
+	if len(s.AnyOf) > 0 {
+		return doc.synthesize(s.AnyOf[0], depth+1)
+	}
+
+	switch s.schemaType() {
+	case "object":
+		obj := map[string]any{}
+		for name, prop := range s.Properties {
+			obj[name] = doc.synthesize(prop, depth+1)
+		}
+		return obj
+	case "array":
+		if s.Items == nil {
+			return []any{}
+		}
+		return []any{doc.synthesize(s.Items, depth+1)}
+	case "integer", "number":
+		return 0
+	case "boolean":
+		return true
+	case "string":
+		return openAPIStringExample(s.Format)
+	default:
+		return nil
+	}
+}
+
+func openAPIStringExample(format string) string {
+	switch format {
+	case "date":
+		return "2024-01-01"
+	case "date-time":
+		return "2024-01-01T00:00:00Z"
+	case "uuid":
+		return "00000000-0000-0000-0000-000000000000"
+	case "email":
+		return "user@example.com"
+	case "uri", "url":
+		return "https://example.com"
+	default:
+		return "string"
+	}
+}
+
+// refName returns the name of a local component reference, e.g. 'Pet' for '#/components/schemas/Pet'.
+func refName(ref, kind string) string {
+	return strings.TrimPrefix(ref, fmt.Sprintf("#/components/%s/", kind))
+}
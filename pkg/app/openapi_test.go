@@ -0,0 +1,112 @@
+package app
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportOpenAPI(t *testing.T) {
+	content, err := os.ReadFile("testdata/openapi/petstore.yaml")
+	require.NoError(t, err)
+
+	imp, err := ImportOpenAPI(content, "")
+	require.NoError(t, err)
+
+	assert.Equal(t, "pet_store", imp.Name)
+	assert.Equal(t, []Mapping{
+		{
+			ID:   "pet_store-listPets",
+			Name: "List all pets",
+			Tags: []string{"pets"},
+			Request: RequestMapping{
+				Method:  "GET",
+				Path:    CommonMatch{Patterns: []string{`^/pets(\?.*)?$`}},
+				Headers: map[string]CommonMatch{"x-tenant": {Patterns: []string{".+"}}},
+			},
+			Response: ResponseMapping{StatusCode: 200, Headers: map[string]string{"Content-Type": "application/json"}, BodyFile: "pet_store/listpets_response.json"},
+		},
+		{
+			ID:       "pet_store-post_pets",
+			Name:     "Create a pet",
+			Tags:     []string{"pets"},
+			Request:  RequestMapping{Method: "POST", Path: CommonMatch{Exact: "/pets"}},
+			Response: ResponseMapping{StatusCode: 201, Headers: map[string]string{"Content-Type": "application/json"}, BodyFile: "pet_store/post_pets_response.json"},
+		},
+		{
+			ID:       "pet_store-deletePet",
+			Request:  RequestMapping{Method: "DELETE", Path: CommonMatch{Patterns: []string{`^/pets/[^/?]+(\?.*)?$`}}},
+			Response: ResponseMapping{StatusCode: 204},
+		},
+		{
+			ID:       "pet_store-showPetById",
+			Request:  RequestMapping{Method: "GET", Path: CommonMatch{Patterns: []string{`^/pets/[^/?]+(\?.*)?$`, `[?&]fields=`}}},
+			Response: ResponseMapping{StatusCode: 200, Headers: map[string]string{"Content-Type": "application/json"}, BodyFile: "pet_store/showpetbyid_response.json"},
+		},
+	}, imp.Mappings)
+
+	assert.JSONEq(t, `[{"id": 0, "name": "string", "status": "available", "birthDate": "2024-01-01"}]`, imp.Bodies["pet_store/listpets_response.json"])
+	assert.JSONEq(t, `{"id": 10, "name": "Rex"}`, imp.Bodies["pet_store/post_pets_response.json"])
+	assert.JSONEq(t, `{"id": 1, "name": "Fido", "tag": "dog"}`, imp.Bodies["pet_store/showpetbyid_response.json"])
+	assert.Len(t, imp.Bodies, 3)
+}
+
+func TestImportOpenAPIUniqueIDs(t *testing.T) {
+	content := `{"openapi": "3.0.0", "info": {"title": "Pets"}, "paths": {
+		"/a": {"get": {"operationId": "getPet", "responses": {"200": {"content": {"application/json": {"example": {"a": 1}}}}}}},
+		"/b": {"get": {"operationId": "GetPet", "responses": {"200": {"content": {"application/json": {"example": {"b": 2}}}}}}},
+		"/c": {"get": {"operationId": "get_pet"}, "post": {"operationId": "get-pet"}}
+	}}`
+
+	imp, err := ImportOpenAPI([]byte(content), "")
+	require.NoError(t, err)
+
+	ids := make([]string, 0, len(imp.Mappings))
+	for _, m := range imp.Mappings {
+		ids = append(ids, m.ID)
+	}
+	assert.Equal(t, []string{"pets-getPet", "pets-GetPet_2", "pets-get_pet", "pets-get-pet_2"}, ids)
+	assert.Equal(t, "pets/getpet_response.json", imp.Mappings[0].Response.BodyFile)
+	assert.Equal(t, "pets/getpet_2_response.json", imp.Mappings[1].Response.BodyFile)
+	assert.Len(t, imp.Bodies, 2)
+
+	dir := t.TempDir()
+	written, err := NewImporterWithPaths(dir+"/mappings", dir+"/responses").Write(imp)
+	require.NoError(t, err)
+	assert.Len(t, written, 6)
+}
+
+func TestImportOpenAPIErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr error
+	}{
+		{
+			name:    "Should fail for OpenAPI 2 documents",
+			content: `{"swagger": "2.0", "paths": {}}`,
+			wantErr: errors.New("unsupported OpenAPI version '', only 3.x documents are supported"),
+		},
+		{
+			name:    "Should fail for missing references",
+			content: `{"openapi": "3.0.0", "paths": {"/a": {"get": {"parameters": [{"$ref": "#/components/parameters/Missing"}]}}}}`,
+			wantErr: errors.New("error importing operation 'GET /a': parameter reference '#/components/parameters/Missing' not found"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ImportOpenAPI([]byte(tt.content), "")
+			assert.EqualError(t, err, tt.wantErr.Error())
+		})
+	}
+}
+
+func TestOpenAPIPathPattern(t *testing.T) {
+	pattern := openAPIPathPattern("/stores/{storeId}/items/{item.id}.json")
+
+	assert.Equal(t, `^/stores/[^/?]+/items/[^/?]+\.json(\?.*)?$`, pattern)
+}
@@ -18,6 +18,20 @@ func NewRegexCache() *RegexCache {
 
 func (r *RegexCache) AddFromMapping(mapping Mapping) error {
 	var err error
+	for _, p := range mapping.Request.Host.Patterns {
+		err = r.compileAndPut(hostPattern(p))
+		if err != nil {
+			return errors.Wrapf(err, "failed to compile host regex with pattern:  %s ", p)
+		}
+	}
+
+	for _, p := range mapping.Request.ClientCertificate.Patterns {
+		err = r.compileAndPut(p)
+		if err != nil {
+			return errors.Wrapf(err, "failed to compile client certificate regex with pattern:  %s ", p)
+		}
+	}
+
 	for _, p := range mapping.Request.Path.Patterns {
 		err = r.compileAndPut(p)
 		if err != nil {
@@ -32,6 +46,17 @@ func (r *RegexCache) AddFromMapping(mapping Mapping) error {
 		}
 	}
 
+	if mapping.WebSocket != nil {
+		for _, m := range mapping.WebSocket.Messages {
+			for _, p := range m.Match.Patterns {
+				err = r.compileAndPut(p)
+				if err != nil {
+					return errors.Wrapf(err, "failed to compile websocket message regex with pattern:  %s ", p)
+				}
+			}
+		}
+	}
+
 	for _, value := range mapping.Request.Headers {
 		for _, p := range value.Patterns {
 			err = r.compileAndPut(p)
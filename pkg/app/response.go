@@ -0,0 +1,117 @@
+package app
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"sync"
+)
+
+const (
+	RandomSelection   = "random"
+	SequenceSelection = "sequence"
+
+	ResponseSelectionModeMessage = "Selection mode must be either 'random' or 'sequence'"
+	ResponseWeightMessage        = "Response weight must not be negative"
+	ResponseNoWeightMessage      = "At least one response must have a positive weight"
+	ResponseMultipleMessage      = "Only one of 'response' or 'responses' can be defined"
+)
+
+// ResponseSelection defines how one of the response variants of a mapping is chosen.
+//
+// Random picks a response based on its weight, where a weight of 0 switches the response off.
+// Sequence returns the responses in order, starting over when loop is true or repeating the last response otherwise.
+type ResponseSelection struct {
+	Mode string `json:"mode"`
+	Loop bool   `json:"loop,omitempty"`
+}
+
+func (s ResponseSelection) Validate(responses []ResponseMapping) ValidationErrors {
+	errs := make(ValidationErrors, 0)
+	if s.Mode != RandomSelection && s.Mode != SequenceSelection {
+		errs = append(errs, ValidationError{"ResponseSelection.Mode", ResponseSelectionModeMessage})
+	}
+
+	var total int
+	for i, r := range responses {
+		if r.EffectiveWeight() < 0 {
+			errs = append(errs, ValidationError{fmt.Sprintf("Responses[%d].Weight", i), ResponseWeightMessage})
+			continue
+		}
+		total += r.EffectiveWeight()
+	}
+
+	if s.Mode == RandomSelection && total == 0 {
+		errs = append(errs, ValidationError{"Responses", ResponseNoWeightMessage})
+	}
+
+	return errs
+}
+
+// ResponseSelector chooses the response for mappings with multiple responses,
+// keeping a counter per mapping for sequential selection.
+type ResponseSelector struct {
+	mu       sync.Mutex
+	counters map[string]int
+	intN     func(int) int
+}
+
+func NewResponseSelector() *ResponseSelector {
+	return &ResponseSelector{
+		counters: make(map[string]int),
+		intN:     rand.IntN,
+	}
+}
+
+// Select returns the response to be used for the mapping.
+func (s *ResponseSelector) Select(m *Mapping) ResponseMapping {
+	if len(m.Responses) == 0 {
+		return m.Response
+	}
+
+	switch m.ResponseSelection.Mode {
+	case RandomSelection:
+		return s.random(m.Responses)
+	default:
+		return s.next(m)
+	}
+}
+
+// Reset starts all sequences over.
+func (s *ResponseSelector) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.counters = make(map[string]int)
+}
+
+func (s *ResponseSelector) next(m *Mapping) ResponseMapping {
+	key := m.Key()
+
+	s.mu.Lock()
+	n := s.counters[key]
+	s.counters[key]++
+	s.mu.Unlock()
+
+	if m.ResponseSelection.Loop {
+		return m.Responses[n%len(m.Responses)]
+	}
+
+	return m.Responses[min(n, len(m.Responses)-1)]
+}
+
+func (s *ResponseSelector) random(responses []ResponseMapping) ResponseMapping {
+	var total int
+	for _, r := range responses {
+		total += r.EffectiveWeight()
+	}
+
+	n := s.intN(total)
+	for _, r := range responses {
+		n -= r.EffectiveWeight()
+		if n < 0 {
+			return r
+		}
+	}
+
+	return responses[len(responses)-1]
+}
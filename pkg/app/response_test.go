@@ -0,0 +1,170 @@
+package app
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResponseSelectorSequence(t *testing.T) {
+	responses := []ResponseMapping{{StatusCode: 200}, {StatusCode: 500}, {StatusCode: 503}}
+
+	tests := []struct {
+		name string
+		loop bool
+		want []int
+	}{
+		{name: "Should loop through responses", loop: true, want: []int{200, 500, 503, 200, 500}},
+		{name: "Should stick on last response", loop: false, want: []int{200, 500, 503, 503, 503}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selector := NewResponseSelector()
+			mapping := Mapping{Responses: responses, ResponseSelection: ResponseSelection{Mode: SequenceSelection, Loop: tt.loop}, FilePath: "file_1"}
+
+			got := make([]int, 0)
+			for range tt.want {
+				got = append(got, selector.Select(&mapping).StatusCode)
+			}
+			assert.Equal(t, tt.want, got)
+
+			selector.Reset()
+			assert.Equal(t, 200, selector.Select(&mapping).StatusCode)
+		})
+	}
+}
+
+func TestResponseSelectorKeepsCounterPerMapping(t *testing.T) {
+	selector := NewResponseSelector()
+	responses := []ResponseMapping{{StatusCode: 200}, {StatusCode: 500}}
+	first := Mapping{Responses: responses, ResponseSelection: ResponseSelection{Mode: SequenceSelection}, FilePath: "file_1"}
+	second := Mapping{Responses: responses, ResponseSelection: ResponseSelection{Mode: SequenceSelection}, FilePath: "file_1", Index: 1}
+
+	assert.Equal(t, 200, selector.Select(&first).StatusCode)
+	assert.Equal(t, 500, selector.Select(&first).StatusCode)
+	assert.Equal(t, 200, selector.Select(&second).StatusCode)
+}
+
+func TestResponseSelectorConcurrentSequence(t *testing.T) {
+	selector := NewResponseSelector()
+	mapping := Mapping{
+		Responses:         []ResponseMapping{{StatusCode: 200}, {StatusCode: 500}},
+		ResponseSelection: ResponseSelection{Mode: SequenceSelection, Loop: true},
+	}
+
+	var mu sync.Mutex
+	counts := make(map[int]int)
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			status := selector.Select(&mapping).StatusCode
+			mu.Lock()
+			counts[status]++
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, map[int]int{200: 50, 500: 50}, counts)
+}
+
+func TestResponseSelectorRandom(t *testing.T) {
+	selector := NewResponseSelector()
+	mapping := Mapping{
+		Responses:         []ResponseMapping{{StatusCode: 200, Weight: weight(9)}, {StatusCode: 500}, {StatusCode: 503, Weight: weight(0)}},
+		ResponseSelection: ResponseSelection{Mode: RandomSelection},
+	}
+
+	tests := []struct {
+		n    int
+		want int
+	}{
+		{n: 0, want: 200},
+		{n: 8, want: 200},
+		{n: 9, want: 500},
+	}
+
+	for _, tt := range tests {
+		selector.intN = func(total int) int {
+			assert.Equal(t, 10, total)
+			return tt.n
+		}
+		assert.Equal(t, tt.want, selector.Select(&mapping).StatusCode)
+	}
+}
+
+func TestResponseSelectorSingleResponse(t *testing.T) {
+	selector := NewResponseSelector()
+	mapping := Mapping{Response: ResponseMapping{StatusCode: 204}}
+
+	assert.Equal(t, 204, selector.Select(&mapping).StatusCode)
+}
+
+func TestValidateResponses(t *testing.T) {
+	tests := []struct {
+		name    string
+		mapping Mapping
+		want    error
+	}{
+		{
+			name: "Should accept valid responses",
+			mapping: Mapping{
+				Request:           RequestMapping{Method: "GET", Path: CommonMatch{Exact: "/"}},
+				Responses:         []ResponseMapping{{StatusCode: 200}},
+				ResponseSelection: ResponseSelection{Mode: RandomSelection},
+			},
+		},
+		{
+			name: "Should reject invalid mode, negative weights and both response types",
+			mapping: Mapping{
+				Request:           RequestMapping{Method: "GET", Path: CommonMatch{Exact: "/"}},
+				Response:          ResponseMapping{StatusCode: 200},
+				Responses:         []ResponseMapping{{StatusCode: 200, Weight: weight(-1)}},
+				ResponseSelection: ResponseSelection{Mode: "roulette"},
+			},
+			want: ValidationErrors{
+				{"Responses", ResponseMultipleMessage},
+				{"ResponseSelection.Mode", ResponseSelectionModeMessage},
+				{"Responses[0].Weight", ResponseWeightMessage},
+			},
+		},
+		{
+			name: "Should reject random responses without a positive weight",
+			mapping: Mapping{
+				Request:           RequestMapping{Method: "GET", Path: CommonMatch{Exact: "/"}},
+				Responses:         []ResponseMapping{{StatusCode: 200, Weight: weight(0)}, {StatusCode: 500, Weight: weight(0)}},
+				ResponseSelection: ResponseSelection{Mode: RandomSelection},
+			},
+			want: ValidationErrors{
+				{"Responses", ResponseNoWeightMessage},
+			},
+		},
+		{
+			name: "Should accept sequence responses without a positive weight",
+			mapping: Mapping{
+				Request:           RequestMapping{Method: "GET", Path: CommonMatch{Exact: "/"}},
+				Responses:         []ResponseMapping{{StatusCode: 200, Weight: weight(0)}},
+				ResponseSelection: ResponseSelection{Mode: SequenceSelection},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.mapping.Validate()
+			if tt.want == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.Equal(t, tt.want, err)
+		})
+	}
+}
+
+func weight(w int) *int {
+	return &w
+}
@@ -2,7 +2,13 @@ package app
 
 import (
 	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/americanas-go/config"
+	"github.com/americanas-go/log"
 	"github.com/ohler55/ojg/oj"
 )
 
@@ -11,17 +17,218 @@ const (
 	ScenarioNoStartingStateMessage       = "the scenario has no starting state defined"
 	ScenarioInvalidStateNameMessage      = "the scenario has a state pointing to a new state that is not defined in the scenario: [%s -> %s]"
 	ScenarioSingleStateMessage           = "the scenario must have at least 2 defined states"
+	ScenarioIsolationConflictMessage     = "the scenario has states with different isolation keys"
+	ScenarioExpiryConflictMessage        = "the scenario has states with different expiry settings"
+	ScenarioStateExpiryConflictMessage   = "the state [%s] has mappings with different state expiry settings"
+	ScenarioInvalidResetStateMessage     = "the scenario expires to a state that is not defined in the scenario: [%s]"
+	ScenarioTriggerNotFoundMessage       = "the scenario is not defined, but is the target of a transition in [%s]"
+	ScenarioInvalidTriggerStateMessage   = "the scenario is the target of a transition in [%s] with a state that is not defined in the scenario: [%s]"
+	ScenarioUnreachableStateMessage      = "the state [%s] can't be reached from the starting state"
+	ScenarioDeadEndMessage               = "the scenario never leaves the state [%s]"
+
+	ScenarioIsolationMessage = "Scenario isolation must define one of 'header' or 'cookie'"
+	ScenarioExpiryMessage    = "Scenario expiry must define a positive 'inactivity' or 'requests'"
+	ScenarioTriggerMessage   = "Transition must define 'scenario' and 'newState'"
+
+	// DefaultScenarioIdleTimeout is how long the state of an isolated scenario is kept for a key without requests.
+	DefaultScenarioIdleTimeout = 10 * time.Minute
 )
 
+// ScenarioIsolation keeps an independent state of the scenario for each value of a request header or cookie,
+// so parallel clients going through the same scenario don't change each other's state.
+type ScenarioIsolation struct {
+	Header string `json:"header,omitempty"`
+	Cookie string `json:"cookie,omitempty"`
+}
+
+func (i *ScenarioIsolation) Validate() ValidationErrors {
+	errs := make(ValidationErrors, 0)
+	if (i.Header == "") == (i.Cookie == "") {
+		errs = append(errs, ValidationError{"Scenario.Isolation", ScenarioIsolationMessage})
+	}
+	return errs
+}
+
+// key returns the isolation key of the request, empty when the request doesn't have one.
+func (i *ScenarioIsolation) key(r Request) string {
+	if i.Header != "" {
+		return r.Headers[strings.ToLower(i.Header)]
+	}
+
+	header := http.Header{"Cookie": []string{r.Headers["cookie"]}}
+	cookie, err := (&http.Request{Header: header}).Cookie(i.Cookie)
+	if err != nil {
+		return ""
+	}
+	return cookie.Value
+}
+
+// ScenarioExpiry resets a scenario, to its starting state or to the ResetTo state, after a period without
+// requests matching the scenario or after a number of requests matched in the same state.
+type ScenarioExpiry struct {
+	Inactivity Duration `json:"inactivity,omitempty"`
+	Requests   int      `json:"requests,omitempty"`
+	ResetTo    string   `json:"resetTo,omitempty"`
+}
+
+func (e *ScenarioExpiry) Validate(field string) ValidationErrors {
+	errs := make(ValidationErrors, 0)
+	if e.Inactivity < 0 || e.Requests < 0 || (e.Inactivity == 0 && e.Requests == 0) {
+		errs = append(errs, ValidationError{field, ScenarioExpiryMessage})
+	}
+	return errs
+}
+
+// expired reports whether the state, with its requests counted since it was entered, must be reset.
+// A state that was never requested does not expire.
+func (e *ScenarioExpiry) expired(c *clientState, now time.Time) bool {
+	if c.lastSeen.IsZero() {
+		return false
+	}
+	if e.Inactivity > 0 && now.Sub(c.lastSeen) >= time.Duration(e.Inactivity) {
+		return true
+	}
+	return e.Requests > 0 && c.requests >= e.Requests
+}
+
+// ScenarioTrigger moves a scenario to a new state when the mapping defining it is matched, from any mapping,
+// in a scenario or not. When State is defined, the transition only happens while the scenario is in that state.
+type ScenarioTrigger struct {
+	Scenario string `json:"scenario"`
+	State    string `json:"state,omitempty"`
+	NewState string `json:"newState"`
+}
+
+func (t ScenarioTrigger) Validate() ValidationErrors {
+	errs := make(ValidationErrors, 0)
+	if t.Scenario == "" || t.NewState == "" {
+		errs = append(errs, ValidationError{"Transitions", ScenarioTriggerMessage})
+	}
+	return errs
+}
+
+// ScenarioState holds the current state of a scenario and its mappings, by state.
 type ScenarioState struct {
 	CurrentState string
-	States       map[string]Mapping
+	States       map[string][]Mapping
+	Isolation    *ScenarioIsolation
+	Expiry       *ScenarioExpiry
+
+	// clients holds the state of an isolated scenario by isolation key.
+	clients map[string]*clientState
+
+	lastSeen time.Time
+	requests int
+}
+
+// clientState is the state of a scenario for one client: the current state, when the scenario was last
+// requested and how many requests were matched since the current state was entered.
+type clientState struct {
+	current  string
+	lastSeen time.Time
+	requests int
+}
+
+// advance records a request matched by the scenario, moving it to the new state, if any.
+func (c *clientState) advance(newState string, now time.Time) {
+	if newState != "" && newState != c.current {
+		c.moveTo(newState, now)
+		return
+	}
+	c.lastSeen = now
+	c.requests++
+}
+
+func (c *clientState) moveTo(state string, now time.Time) {
+	c.current, c.lastSeen, c.requests = state, now, 0
+}
+
+func (sc ScenarioState) shared() *clientState {
+	return &clientState{current: sc.CurrentState, lastSeen: sc.lastSeen, requests: sc.requests}
+}
+
+func (sc *ScenarioState) setShared(c *clientState) {
+	sc.CurrentState, sc.lastSeen, sc.requests = c.current, c.lastSeen, c.requests
+}
+
+// expiry returns the expiry of the state, defined by any of its mappings, which overrides the expiry of the scenario.
+// All mappings of a state defining it must use the same expiry, see ValidateScenarioStates.
+func (sc ScenarioState) expiry(state string) *ScenarioExpiry {
+	for _, m := range sc.States[state] {
+		if m.Scenario.StateExpiry != nil {
+			return m.Scenario.StateExpiry
+		}
+	}
+	return sc.Expiry
+}
+
+// reset moves the client back to the reset state of the scenario when its current state expired,
+// reporting whether it did.
+func (sc ScenarioState) reset(c *clientState, now time.Time) bool {
+	expiry := sc.expiry(c.current)
+	if expiry == nil || !expiry.expired(c, now) {
+		return false
+	}
+
+	state := expiry.ResetTo
+	if state == "" {
+		state = sc.startingState()
+	}
+	c.moveTo(state, now)
+	return true
+}
+
+// startingState returns the name of the starting state of the scenario.
+func (sc ScenarioState) startingState() string {
+	if names := sc.startingStates(); len(names) > 0 {
+		return names[0]
+	}
+	return ""
+}
+
+// startingStates returns the names of the states with a mapping marked as the starting state.
+func (sc ScenarioState) startingStates() []string {
+	names := make([]string, 0, 1)
+	for name, mappings := range sc.States {
+		for _, m := range mappings {
+			if m.Scenario.StartingState {
+				names = append(names, name)
+				break
+			}
+		}
+	}
+	return names
+}
+
+// client returns the state of the scenario for the isolation key, starting a new one when the key has none,
+// or the shared state when the key is empty. The shared state must be stored back with setShared.
+func (sc ScenarioState) client(key string) *clientState {
+	if key == "" {
+		return sc.shared()
+	}
+
+	client, ok := sc.clients[key]
+	if !ok {
+		client = &clientState{current: sc.startingState()}
+		sc.clients[key] = client
+	}
+	return client
 }
 
 type ScenarioHandler struct {
 	matcher          *Matcher
 	scenarioMappings Mappings
 	scenarios        map[string]ScenarioState
+
+	// triggers holds the mappings with transitions, so their targets can be validated.
+	triggers []Mapping
+
+	mu           sync.Mutex
+	isolated     bool
+	expires      bool
+	idleTimeout  time.Duration
+	lastEviction time.Time
+	now          func() time.Time
 }
 
 type ScenarioValidationError struct {
@@ -36,10 +243,17 @@ func (v ScenarioValidationErrors) Error() string {
 }
 
 func NewScenarioHandler(matcher *Matcher) *ScenarioHandler {
+	idleTimeout := config.Duration("scenario.isolation.idleTimeout")
+	if idleTimeout <= 0 {
+		idleTimeout = DefaultScenarioIdleTimeout
+	}
+
 	return &ScenarioHandler{
 		scenarios:        map[string]ScenarioState{},
 		scenarioMappings: make(Mappings),
 		matcher:          matcher,
+		idleTimeout:      idleTimeout,
+		now:              time.Now,
 	}
 }
 
@@ -57,21 +271,108 @@ func (hand *ScenarioHandler) AddScenario(mapping Mapping) {
 	}
 
 	if len(sc.States) == 0 {
-		sc.States = make(map[string]Mapping)
+		sc.States = make(map[string][]Mapping)
 	}
 
 	if scMapping.StartingState {
 		sc.CurrentState = scMapping.State
 	}
 
-	sc.States[scMapping.State] = mapping
+	if scMapping.Isolation != nil && sc.Isolation == nil {
+		sc.Isolation = scMapping.Isolation
+		sc.clients = make(map[string]*clientState)
+		hand.isolated = true
+	}
+
+	if scMapping.Expiry != nil && sc.Expiry == nil {
+		sc.Expiry = scMapping.Expiry
+	}
+
+	if scMapping.Expiry != nil || scMapping.StateExpiry != nil {
+		hand.expires = true
+	}
+
+	sc.States[scMapping.State] = append(sc.States[scMapping.State], mapping)
 
 	hand.scenarios[scMapping.Name] = sc
 	hand.scenarioMappings.Put(mapping)
 }
 
 func (hand *ScenarioHandler) MatchScenario(request Request) (Mapping, bool, bool) {
-	mapping, matched, partial := hand.matcher.Match(request, hand.scenarioMappings, hand.scenarios)
+	hand.mu.Lock()
+	defer hand.mu.Unlock()
+
+	now := hand.now()
+	hand.evictIdle(now)
+	hand.expire(request, now)
+
+	result, matched, partial := hand.peek(request)
+	if !matched {
+		return result, matched, partial
+	}
+
+	hand.update(result.Scenario.Name, request, func(c *clientState) {
+		c.advance(result.Scenario.NewState, now)
+	})
+	return result, matched, partial
+}
+
+// AddTriggers keeps the mapping when it has transitions, to validate their target scenarios and states.
+func (hand *ScenarioHandler) AddTriggers(mapping Mapping) {
+	if len(mapping.Transitions) > 0 {
+		hand.triggers = append(hand.triggers, mapping)
+	}
+}
+
+// Trigger moves the scenarios targeted by the transitions of a matched mapping, for the isolation key
+// of the request, returning the transitions made.
+func (hand *ScenarioHandler) Trigger(request Request, transitions []ScenarioTrigger) []ScenarioTransition {
+	hand.mu.Lock()
+	defer hand.mu.Unlock()
+
+	now := hand.now()
+	made := make([]ScenarioTransition, 0, len(transitions))
+	for _, t := range transitions {
+		hand.update(t.Scenario, request, func(c *clientState) {
+			if t.State != "" && c.current != t.State {
+				return
+			}
+			made = append(made, ScenarioTransition{Name: t.Scenario, From: c.current, To: t.NewState})
+			c.moveTo(t.NewState, now)
+		})
+	}
+	return made
+}
+
+// update changes the state of the scenario for the isolation key of the request, or its shared state.
+func (hand *ScenarioHandler) update(name string, request Request, change func(*clientState)) {
+	sc, ok := hand.scenarios[name]
+	if !ok {
+		return
+	}
+
+	key := hand.isolationKey(sc, request)
+	c := sc.client(key)
+	change(c)
+	if key == "" {
+		sc.setShared(c)
+		hand.scenarios[name] = sc
+	}
+}
+
+// PeekScenario matches the request against the current state of the scenarios, like MatchScenario,
+// but without moving the matched scenario to its new state.
+func (hand *ScenarioHandler) PeekScenario(request Request) (Mapping, bool, bool) {
+	hand.mu.Lock()
+	defer hand.mu.Unlock()
+
+	hand.expire(request, hand.now())
+	return hand.peek(request)
+}
+
+func (hand *ScenarioHandler) peek(request Request) (Mapping, bool, bool) {
+	states := hand.statesFor(request)
+	mapping, matched, partial := hand.matcher.Match(request, hand.scenarioMappings, states)
 	if !matched || partial {
 		return Mapping{}, false, false
 	}
@@ -79,40 +380,148 @@ func (hand *ScenarioHandler) MatchScenario(request Request) (Mapping, bool, bool
 	if mapping.Scenario == nil {
 		return Mapping{}, false, false
 	}
-	state := hand.scenarios[mapping.Scenario.Name]
+	state := states[mapping.Scenario.Name]
 	if mapping.Scenario.State != state.CurrentState {
 		return Mapping{}, false, true
 	}
-	result := state.States[state.CurrentState]
-	if result.Scenario.NewState != "" {
-		state.CurrentState = result.Scenario.NewState
-		hand.scenarios[mapping.Scenario.Name] = state
+	return mapping, true, false
+}
+
+// CurrentStates returns the current state of each scenario as seen by the request.
+func (hand *ScenarioHandler) CurrentStates(request Request) map[string]string {
+	hand.mu.Lock()
+	defer hand.mu.Unlock()
+
+	states := make(map[string]string, len(hand.scenarios))
+	for name, sc := range hand.statesFor(request) {
+		states[name] = sc.CurrentState
+	}
+	return states
+}
+
+// statesFor returns the current state of the scenarios as seen by the request: isolated scenarios are in the
+// state of the isolation key of the request, or in their starting state for a key without requests yet.
+// Requests without the key share the state of the scenario.
+func (hand *ScenarioHandler) statesFor(request Request) map[string]ScenarioState {
+	if !hand.isolated {
+		return hand.scenarios
+	}
+
+	states := make(map[string]ScenarioState, len(hand.scenarios))
+	for name, sc := range hand.scenarios {
+		if key := hand.isolationKey(sc, request); key != "" {
+			if client, ok := sc.clients[key]; ok {
+				sc.CurrentState = client.current
+			} else {
+				sc.CurrentState = sc.startingState()
+			}
+		}
+		states[name] = sc
+	}
+	return states
+}
+
+func (hand *ScenarioHandler) isolationKey(sc ScenarioState, request Request) string {
+	if sc.Isolation == nil {
+		return ""
+	}
+	return sc.Isolation.key(request)
+}
+
+// expire resets the scenarios whose current state expired, along with the state of the isolated
+// scenarios for the isolation key of the request.
+func (hand *ScenarioHandler) expire(request Request, now time.Time) {
+	if !hand.expires {
+		return
+	}
+
+	for name, sc := range hand.scenarios {
+		shared := sc.shared()
+		if sc.reset(shared, now) {
+			sc.setShared(shared)
+			hand.scenarios[name] = sc
+		}
+
+		if key := hand.isolationKey(sc, request); key != "" {
+			if client, ok := sc.clients[key]; ok {
+				sc.reset(client, now)
+			}
+		}
+	}
+}
+
+// evictIdle removes the state kept for isolation keys without requests for longer than the idle timeout,
+// checking at most once per timeout.
+func (hand *ScenarioHandler) evictIdle(now time.Time) {
+	if !hand.isolated || now.Sub(hand.lastEviction) < hand.idleTimeout {
+		return
+	}
+	hand.lastEviction = now
+
+	for _, sc := range hand.scenarios {
+		for key, client := range sc.clients {
+			if now.Sub(client.lastSeen) >= hand.idleTimeout {
+				delete(sc.clients, key)
+			}
+		}
 	}
-	return result, true, false
 }
 
 // Validates the following:
 //
-//   - Each scenario has exactly one starting state
+//   - Each scenario has exactly one starting state, which can have several mappings
 //   - Each scenario has at least 2 states
 //   - State names are valid inside each scenario
+//   - All states of an isolated scenario use the same isolation key
+//   - All states of a scenario use the same scenario expiry, and expire to states defined in the scenario
+//   - All mappings of a state use the same state expiry
+//   - Transitions target scenarios and states that are defined
+//
+// States that can't be reached from the starting state and dead ends, states the scenario never leaves,
+// are logged as warnings for the scenarios without errors.
 func (hand *ScenarioHandler) ValidateScenarioStates() error {
 	errors := make(ScenarioValidationErrors, 0)
 
 	for k, v := range hand.scenarios {
-		startingStates := []string{}
-		for _, s := range v.States {
-			if s.Scenario.StartingState {
-				startingStates = append(startingStates, s.Scenario.State)
-			}
+		isolationConflict, expiryConflict := false, false
+		for state, mappings := range v.States {
+			var stateExpiry *ScenarioExpiry
+			stateExpiryConflict := false
+			for _, s := range mappings {
+				if s.Scenario.Isolation != nil && *s.Scenario.Isolation != *v.Isolation {
+					isolationConflict = true
+				}
+
+				if s.Scenario.Expiry != nil && *s.Scenario.Expiry != *v.Expiry {
+					expiryConflict = true
+				}
+
+				if s.Scenario.StateExpiry != nil {
+					if stateExpiry != nil && *s.Scenario.StateExpiry != *stateExpiry {
+						stateExpiryConflict = true
+					}
+					stateExpiry = s.Scenario.StateExpiry
+				}
 
-			if s.Scenario.NewState != "" {
-				if _, ok := v.States[s.Scenario.NewState]; !ok {
-					errors = append(errors, ScenarioValidationError{k, fmt.Sprintf(ScenarioInvalidStateNameMessage, s.Scenario.State, s.Scenario.NewState)})
+				if s.Scenario.StateExpiry != nil && s.Scenario.StateExpiry.ResetTo != "" {
+					if _, ok := v.States[s.Scenario.StateExpiry.ResetTo]; !ok {
+						errors = append(errors, ScenarioValidationError{k, fmt.Sprintf(ScenarioInvalidResetStateMessage, s.Scenario.StateExpiry.ResetTo)})
+					}
+				}
+
+				if s.Scenario.NewState != "" {
+					if _, ok := v.States[s.Scenario.NewState]; !ok {
+						errors = append(errors, ScenarioValidationError{k, fmt.Sprintf(ScenarioInvalidStateNameMessage, s.Scenario.State, s.Scenario.NewState)})
+					}
 				}
 			}
+
+			if stateExpiryConflict {
+				errors = append(errors, ScenarioValidationError{k, fmt.Sprintf(ScenarioStateExpiryConflictMessage, state)})
+			}
 		}
 
+		startingStates := v.startingStates()
 		if len(startingStates) == 0 {
 			errors = append(errors, ScenarioValidationError{k, ScenarioNoStartingStateMessage})
 		}
@@ -124,6 +533,53 @@ func (hand *ScenarioHandler) ValidateScenarioStates() error {
 		if len(v.States) <= 1 {
 			errors = append(errors, ScenarioValidationError{k, ScenarioSingleStateMessage})
 		}
+
+		if isolationConflict {
+			errors = append(errors, ScenarioValidationError{k, ScenarioIsolationConflictMessage})
+		}
+
+		if expiryConflict {
+			errors = append(errors, ScenarioValidationError{k, ScenarioExpiryConflictMessage})
+		}
+
+		if v.Expiry != nil && v.Expiry.ResetTo != "" {
+			if _, ok := v.States[v.Expiry.ResetTo]; !ok {
+				errors = append(errors, ScenarioValidationError{k, fmt.Sprintf(ScenarioInvalidResetStateMessage, v.Expiry.ResetTo)})
+			}
+		}
+	}
+
+	for _, m := range hand.triggers {
+		for _, t := range m.Transitions {
+			sc, ok := hand.scenarios[t.Scenario]
+			if !ok {
+				errors = append(errors, ScenarioValidationError{t.Scenario, fmt.Sprintf(ScenarioTriggerNotFoundMessage, m.FilePath)})
+				continue
+			}
+
+			for _, state := range []string{t.State, t.NewState} {
+				if _, ok := sc.States[state]; state != "" && !ok {
+					errors = append(errors, ScenarioValidationError{t.Scenario, fmt.Sprintf(ScenarioInvalidTriggerStateMessage, m.FilePath, state)})
+				}
+			}
+		}
+	}
+
+	invalid := make(map[string]bool)
+	for _, e := range errors {
+		invalid[e.ScenarioName] = true
+	}
+	for name, sc := range hand.scenarios {
+		if invalid[name] {
+			continue
+		}
+		g := hand.graph(name, sc)
+		for _, state := range g.UnreachableStates() {
+			log.Warnf("scenario '%s': %s", name, fmt.Sprintf(ScenarioUnreachableStateMessage, state))
+		}
+		for _, state := range g.DeadEnds() {
+			log.Warnf("scenario '%s': %s", name, fmt.Sprintf(ScenarioDeadEndMessage, state))
+		}
 	}
 
 	if len(errors) > 0 {
@@ -3,6 +3,7 @@ package app
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -125,12 +126,59 @@ func TestValidateScenarios(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:  "validates scenario with different isolation keys",
+			input: invalidScenarios["isolationConflict"],
+			want: ScenarioValidationErrors{
+				{
+					ScenarioName: "Isolation Conflict",
+					Message:      "the scenario has states with different isolation keys",
+				},
+			},
+		},
+		{
+			name:  "validates transitions targeting undefined scenarios and states",
+			input: invalidScenarios["triggerErrors"],
+			want: ScenarioValidationErrors{
+				{
+					ScenarioName: "Session",
+					Message:      "the scenario is the target of a transition in [logout.json] with a state that is not defined in the scenario: [Non existent]",
+				}, {
+					ScenarioName: "Non existent",
+					Message:      "the scenario is not defined, but is the target of a transition in [logout.json]",
+				},
+			},
+		},
+		{
+			name:  "validates scenario with invalid expiry",
+			input: invalidScenarios["expiryErrors"],
+			want: ScenarioValidationErrors{
+				{
+					ScenarioName: "Expiry Errors",
+					Message:      "the scenario expires to a state that is not defined in the scenario: [Non existent]",
+				}, {
+					ScenarioName: "Expiry Errors",
+					Message:      "the scenario has states with different expiry settings",
+				},
+			},
+		},
+		{
+			name:  "validates state with different state expiry settings",
+			input: invalidScenarios["stateExpiryConflict"],
+			want: ScenarioValidationErrors{
+				{
+					ScenarioName: "State Expiry Conflict",
+					Message:      "the state [Second] has mappings with different state expiry settings",
+				},
+			},
+		},
 	}
 
 	for _, tt := range tests {
 		handler := NewScenarioHandler(nil)
 		t.Run(tt.name, func(t *testing.T) {
 			for _, m := range tt.input {
+				handler.AddTriggers(m)
 				handler.AddScenario(m)
 			}
 
@@ -181,7 +229,7 @@ func TestScenarioMatching(t *testing.T) {
 						StatusCode: 404,
 						Matched:    false,
 						Headers:    map[string]string{"Content-type": "application/json"},
-						Body:       NotFoundResponse{Message: "No mapping found for the request", Request: Request{Path: "/objects/123", Method: "GET"}},
+						Body:       NotFoundResponse{Message: "No mapping found for the request", Request: Request{Path: "/objects/123", Method: "GET"}, Candidates: []MatchDiff{}},
 					},
 				},
 				{
@@ -219,10 +267,329 @@ func TestScenarioMatching(t *testing.T) {
 	}
 }
 
-func getMappingsMap(mappings []Mapping) map[string]Mapping {
-	res := make(map[string]Mapping)
+func TestScenarioIsolation(t *testing.T) {
+	isolated := func(isolation ScenarioIsolation) []Mapping {
+		mappings := make([]Mapping, 0)
+		for _, m := range validScenarios["firstScenario"] {
+			sc := *m.Scenario
+			sc.Isolation = &isolation
+			m.Scenario = &sc
+			mappings = append(mappings, m)
+		}
+		return mappings
+	}
+
+	newHandler := func(isolation ScenarioIsolation) *ScenarioHandler {
+		handler := NewScenarioHandler(NewMatcher(NewRegexCache(), NewJSONPathCache()))
+		for _, m := range isolated(isolation) {
+			handler.AddScenario(m)
+		}
+		require.NoError(t, handler.ValidateScenarioStates())
+		return handler
+	}
+
+	deleteRequest := func(headers map[string]string) Request {
+		return Request{Method: "DELETE", Path: "/scenario/123", Headers: headers}
+	}
+
+	matchedFile := func(handler *ScenarioHandler, r Request) string {
+		mapping, matched, _ := handler.MatchScenario(r)
+		if !matched {
+			return ""
+		}
+		return mapping.FilePath
+	}
+
+	t.Run("should keep the state of each header value", func(t *testing.T) {
+		handler := newHandler(ScenarioIsolation{Header: "X-Test-Id"})
+		first := deleteRequest(map[string]string{"x-test-id": "first"})
+		second := deleteRequest(map[string]string{"x-test-id": "second"})
+
+		assert.Equal(t, "scenario1_1", matchedFile(handler, first))
+		assert.Equal(t, "scenario1_1", matchedFile(handler, second))
+		assert.Equal(t, "scenario1_2", matchedFile(handler, first))
+		assert.Equal(t, "scenario1_2", matchedFile(handler, second))
+
+		assert.Equal(t, "Object Exists", handler.scenarios["First Scenario"].CurrentState)
+	})
+
+	t.Run("should keep the state of each cookie value", func(t *testing.T) {
+		handler := newHandler(ScenarioIsolation{Cookie: "test"})
+		first := deleteRequest(map[string]string{"cookie": "lang=en; test=first"})
+		second := deleteRequest(map[string]string{"cookie": "test=second"})
+
+		assert.Equal(t, "scenario1_1", matchedFile(handler, first))
+		assert.Equal(t, "scenario1_2", matchedFile(handler, first))
+		assert.Equal(t, "scenario1_1", matchedFile(handler, second))
+	})
+
+	t.Run("should share the state between requests without the key", func(t *testing.T) {
+		handler := newHandler(ScenarioIsolation{Header: "X-Test-Id"})
+		keyed := deleteRequest(map[string]string{"x-test-id": "first"})
+
+		assert.Equal(t, "scenario1_1", matchedFile(handler, deleteRequest(nil)))
+		assert.Equal(t, "scenario1_2", matchedFile(handler, deleteRequest(nil)))
+		assert.Equal(t, "scenario1_1", matchedFile(handler, keyed))
+		assert.Equal(t, "Get Deleted Object", handler.scenarios["First Scenario"].CurrentState)
+	})
+
+	t.Run("should evict keys without requests after the idle timeout", func(t *testing.T) {
+		handler := newHandler(ScenarioIsolation{Header: "X-Test-Id"})
+		now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		handler.now = func() time.Time { return now }
+		handler.idleTimeout = time.Minute
+
+		first := deleteRequest(map[string]string{"x-test-id": "first"})
+		second := deleteRequest(map[string]string{"x-test-id": "second"})
+
+		assert.Equal(t, "scenario1_1", matchedFile(handler, first))
+		now = now.Add(30 * time.Second)
+		assert.Equal(t, "scenario1_1", matchedFile(handler, second))
+
+		now = now.Add(45 * time.Second)
+		assert.Equal(t, "scenario1_1", matchedFile(handler, first))
+		assert.Equal(t, "scenario1_2", matchedFile(handler, second))
+
+		clients := handler.scenarios["First Scenario"].clients
+		assert.Len(t, clients, 2)
+		assert.Equal(t, "Object Deleted", clients["first"].current)
+	})
+}
+
+func TestScenarioExpiry(t *testing.T) {
+	withExpiry := func(name string, set func(*ScenarioMapping)) []Mapping {
+		mappings := make([]Mapping, 0)
+		for _, m := range validScenarios[name] {
+			sc := *m.Scenario
+			set(&sc)
+			m.Scenario = &sc
+			mappings = append(mappings, m)
+		}
+		return mappings
+	}
+
+	type step struct {
+		after   time.Duration
+		request Request
+		want    string
+	}
+
+	deleteObject := Request{Method: "DELETE", Path: "/scenario/123"}
+	getDeleted := Request{Method: "GET", Path: "/scenario/123"}
+	createObject := Request{Method: "POST", Path: "/objects"}
+	getObject := Request{Method: "GET", Path: "/objects/123"}
+
+	tests := []struct {
+		name     string
+		mappings []Mapping
+		steps    []step
+	}{
+		{
+			name: "should reset the scenario after the inactivity period",
+			mappings: withExpiry("firstScenario", func(sc *ScenarioMapping) {
+				sc.Expiry = &ScenarioExpiry{Inactivity: Duration(time.Minute)}
+			}),
+			steps: []step{
+				{request: deleteObject, want: "scenario1_1"},
+				{after: 30 * time.Second, request: deleteObject, want: "scenario1_2"},
+				{after: time.Minute, request: getDeleted, want: ""},
+				{request: deleteObject, want: "scenario1_1"},
+			},
+		},
+		{
+			name: "should not reset a scenario that was never requested",
+			mappings: withExpiry("firstScenario", func(sc *ScenarioMapping) {
+				sc.Expiry = &ScenarioExpiry{Inactivity: Duration(time.Minute), ResetTo: "Get Deleted Object"}
+			}),
+			steps: []step{
+				{after: time.Hour, request: deleteObject, want: "scenario1_1"},
+			},
+		},
+		{
+			name: "should reset the state after the number of requests",
+			mappings: withExpiry("secondScenario", func(sc *ScenarioMapping) {
+				if sc.State == "Object Created" {
+					sc.StateExpiry = &ScenarioExpiry{Requests: 2}
+				}
+			}),
+			steps: []step{
+				{request: createObject, want: "scenario2_1"},
+				{request: getObject, want: "scenario2_2"},
+				{request: getObject, want: "scenario2_2"},
+				{request: getObject, want: ""},
+				{request: createObject, want: "scenario2_1"},
+			},
+		},
+		{
+			name: "should reset the state to the named state, overriding the scenario expiry",
+			mappings: withExpiry("firstScenario", func(sc *ScenarioMapping) {
+				sc.Expiry = &ScenarioExpiry{Inactivity: Duration(time.Hour)}
+				if sc.State == "Get Deleted Object" {
+					sc.StateExpiry = &ScenarioExpiry{Requests: 1, ResetTo: "Object Deleted"}
+				}
+			}),
+			steps: []step{
+				{request: deleteObject, want: "scenario1_1"},
+				{request: deleteObject, want: "scenario1_2"},
+				{request: getDeleted, want: "scenario1_3"},
+				{request: deleteObject, want: "scenario1_2"},
+			},
+		},
+		{
+			name: "should reset the state of each isolation key",
+			mappings: withExpiry("firstScenario", func(sc *ScenarioMapping) {
+				sc.Isolation = &ScenarioIsolation{Header: "X-Test-Id"}
+				sc.Expiry = &ScenarioExpiry{Inactivity: Duration(time.Minute)}
+			}),
+			steps: []step{
+				{request: Request{Method: "DELETE", Path: "/scenario/123", Headers: map[string]string{"x-test-id": "first"}}, want: "scenario1_1"},
+				{after: 45 * time.Second, request: Request{Method: "DELETE", Path: "/scenario/123", Headers: map[string]string{"x-test-id": "second"}}, want: "scenario1_1"},
+				{after: 30 * time.Second, request: Request{Method: "DELETE", Path: "/scenario/123", Headers: map[string]string{"x-test-id": "first"}}, want: "scenario1_1"},
+				{request: Request{Method: "DELETE", Path: "/scenario/123", Headers: map[string]string{"x-test-id": "second"}}, want: "scenario1_2"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewScenarioHandler(NewMatcher(NewRegexCache(), NewJSONPathCache()))
+			for _, m := range tt.mappings {
+				handler.AddScenario(m)
+			}
+			require.NoError(t, handler.ValidateScenarioStates())
+
+			now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			handler.now = func() time.Time { return now }
+
+			for i, s := range tt.steps {
+				now = now.Add(s.after)
+				mapping, matched, _ := handler.MatchScenario(s.request)
+				assert.Equal(t, s.want != "", matched, "step %d", i+1)
+				assert.Equal(t, s.want, mapping.FilePath, "step %d", i+1)
+			}
+		})
+	}
+}
+
+func TestScenarioMultipleMappingsPerState(t *testing.T) {
+	mappings := []Mapping{
+		{
+			Scenario: &ScenarioMapping{Name: "Order", StartingState: true, State: "Open", NewState: "Paid"},
+			Request:  RequestMapping{Method: "POST", Path: CommonMatch{Exact: "/order/pay"}, Body: BodyMatch{CommonMatch: CommonMatch{Contains: []string{"card"}}}},
+			Response: ResponseMapping{StatusCode: 200},
+			FilePath: "pay_card",
+		}, {
+			Scenario: &ScenarioMapping{Name: "Order", StartingState: true, State: "Open", NewState: "Awaiting Payment"},
+			Request:  RequestMapping{Method: "POST", Path: CommonMatch{Exact: "/order/pay"}, Body: BodyMatch{CommonMatch: CommonMatch{Contains: []string{"invoice"}}}},
+			Response: ResponseMapping{StatusCode: 202},
+			FilePath: "pay_invoice",
+		}, {
+			Scenario: &ScenarioMapping{Name: "Order", State: "Open"},
+			Request:  RequestMapping{Method: "GET", Path: CommonMatch{Exact: "/order"}},
+			Response: ResponseMapping{StatusCode: 200},
+			FilePath: "get_open",
+		}, {
+			Scenario: &ScenarioMapping{Name: "Order", State: "Paid"},
+			Request:  RequestMapping{Method: "GET", Path: CommonMatch{Exact: "/order"}},
+			Response: ResponseMapping{StatusCode: 200},
+			FilePath: "get_paid",
+		}, {
+			Scenario: &ScenarioMapping{Name: "Order", State: "Awaiting Payment", NewState: "Paid"},
+			Request:  RequestMapping{Method: "POST", Path: CommonMatch{Exact: "/order/invoice"}},
+			Response: ResponseMapping{StatusCode: 200},
+			FilePath: "invoice_paid",
+		},
+	}
+
+	newHandler := func() *ScenarioHandler {
+		handler := NewScenarioHandler(NewMatcher(NewRegexCache(), NewJSONPathCache()))
+		for _, m := range mappings {
+			m.CalcMaxScoreAndCost()
+			handler.AddScenario(m)
+		}
+		require.NoError(t, handler.ValidateScenarioStates())
+		return handler
+	}
+
+	match := func(handler *ScenarioHandler, r Request) string {
+		mapping, matched, _ := handler.MatchScenario(r)
+		if !matched {
+			return ""
+		}
+		return mapping.FilePath
+	}
+
+	getOrder := Request{Method: "GET", Path: "/order"}
+
+	t.Run("should serve every mapping of the current state", func(t *testing.T) {
+		handler := newHandler()
+		assert.Equal(t, "get_open", match(handler, getOrder))
+		assert.Equal(t, "get_open", match(handler, getOrder))
+		assert.Equal(t, "", match(handler, Request{Method: "POST", Path: "/order/invoice"}))
+	})
+
+	t.Run("should move to the state of the mapping matching the request content", func(t *testing.T) {
+		handler := newHandler()
+		assert.Equal(t, "pay_card", match(handler, Request{Method: "POST", Path: "/order/pay", Body: `{"method": "card"}`}))
+		assert.Equal(t, "get_paid", match(handler, getOrder))
+
+		handler = newHandler()
+		assert.Equal(t, "pay_invoice", match(handler, Request{Method: "POST", Path: "/order/pay", Body: `{"method": "invoice"}`}))
+		assert.Equal(t, "", match(handler, getOrder))
+		assert.Equal(t, "invoice_paid", match(handler, Request{Method: "POST", Path: "/order/invoice"}))
+		assert.Equal(t, "get_paid", match(handler, getOrder))
+	})
+}
+
+func TestScenarioTrigger(t *testing.T) {
+	newHandler := func(isolation *ScenarioIsolation) *ScenarioHandler {
+		handler := NewScenarioHandler(NewMatcher(NewRegexCache(), NewJSONPathCache()))
+		for _, m := range validScenarios["firstScenario"] {
+			sc := *m.Scenario
+			sc.Isolation = isolation
+			m.Scenario = &sc
+			handler.AddScenario(m)
+		}
+		return handler
+	}
+
+	t.Run("should move the scenario to the new state", func(t *testing.T) {
+		handler := newHandler(nil)
+		made := handler.Trigger(Request{}, []ScenarioTrigger{{Scenario: "First Scenario", NewState: "Get Deleted Object"}})
+
+		assert.Equal(t, []ScenarioTransition{{Name: "First Scenario", From: "Object Exists", To: "Get Deleted Object"}}, made)
+		assert.Equal(t, "Get Deleted Object", handler.scenarios["First Scenario"].CurrentState)
+	})
+
+	t.Run("should only move the scenario when in the state of the transition", func(t *testing.T) {
+		handler := newHandler(nil)
+		made := handler.Trigger(Request{}, []ScenarioTrigger{
+			{Scenario: "First Scenario", State: "Object Deleted", NewState: "Get Deleted Object"},
+			{Scenario: "Unknown", NewState: "Any"},
+		})
+
+		assert.Empty(t, made)
+		assert.Equal(t, "Object Exists", handler.scenarios["First Scenario"].CurrentState)
+	})
+
+	t.Run("should move the scenario for the isolation key of the request", func(t *testing.T) {
+		handler := newHandler(&ScenarioIsolation{Header: "X-Test-Id"})
+		first := Request{Method: "GET", Path: "/scenario/123", Headers: map[string]string{"x-test-id": "first"}}
+		second := Request{Method: "GET", Path: "/scenario/123", Headers: map[string]string{"x-test-id": "second"}}
+
+		handler.Trigger(first, []ScenarioTrigger{{Scenario: "First Scenario", NewState: "Get Deleted Object"}})
+
+		_, matched, _ := handler.MatchScenario(first)
+		assert.True(t, matched)
+		_, matched, _ = handler.MatchScenario(second)
+		assert.False(t, matched)
+	})
+}
+
+func getMappingsMap(mappings []Mapping) map[string][]Mapping {
+	res := make(map[string][]Mapping)
 	for _, m := range mappings {
-		res[m.Scenario.State] = m
+		res[m.Scenario.State] = append(res[m.Scenario.State], m)
 	}
 	return res
 }
@@ -275,6 +642,59 @@ var invalidScenarios = map[string][]Mapping{
 			Response: ResponseMapping{StatusCode: 200},
 		},
 	},
+	"isolationConflict": {
+		{
+			Scenario: &ScenarioMapping{Name: "Isolation Conflict", StartingState: true, State: "First", NewState: "Second", Isolation: &ScenarioIsolation{Header: "X-Test-Id"}},
+			Request:  RequestMapping{Method: "GET", Path: CommonMatch{Exact: "/first"}},
+			Response: ResponseMapping{StatusCode: 200},
+		}, {
+			Scenario: &ScenarioMapping{Name: "Isolation Conflict", State: "Second", Isolation: &ScenarioIsolation{Cookie: "session"}},
+			Request:  RequestMapping{Method: "GET", Path: CommonMatch{Exact: "/first"}},
+			Response: ResponseMapping{StatusCode: 404},
+		},
+	},
+	"triggerErrors": {
+		{
+			Request:     RequestMapping{Method: "POST", Path: CommonMatch{Exact: "/logout"}},
+			Response:    ResponseMapping{StatusCode: 204},
+			Transitions: []ScenarioTrigger{{Scenario: "Session", State: "Logged In", NewState: "Non existent"}, {Scenario: "Non existent", NewState: "First"}},
+			FilePath:    "logout.json",
+		}, {
+			Scenario: &ScenarioMapping{Name: "Session", StartingState: true, State: "Logged Out", NewState: "Logged In"},
+			Request:  RequestMapping{Method: "POST", Path: CommonMatch{Exact: "/login"}},
+			Response: ResponseMapping{StatusCode: 200},
+		}, {
+			Scenario: &ScenarioMapping{Name: "Session", State: "Logged In"},
+			Request:  RequestMapping{Method: "GET", Path: CommonMatch{Exact: "/me"}},
+			Response: ResponseMapping{StatusCode: 200},
+		},
+	},
+	"expiryErrors": {
+		{
+			Scenario: &ScenarioMapping{Name: "Expiry Errors", StartingState: true, State: "First", NewState: "Second", Expiry: &ScenarioExpiry{Inactivity: Duration(time.Minute)}},
+			Request:  RequestMapping{Method: "GET", Path: CommonMatch{Exact: "/first"}},
+			Response: ResponseMapping{StatusCode: 200},
+		}, {
+			Scenario: &ScenarioMapping{Name: "Expiry Errors", State: "Second", Expiry: &ScenarioExpiry{Requests: 2}, StateExpiry: &ScenarioExpiry{Requests: 1, ResetTo: "Non existent"}},
+			Request:  RequestMapping{Method: "GET", Path: CommonMatch{Exact: "/first"}},
+			Response: ResponseMapping{StatusCode: 404},
+		},
+	},
+	"stateExpiryConflict": {
+		{
+			Scenario: &ScenarioMapping{Name: "State Expiry Conflict", StartingState: true, State: "First", NewState: "Second"},
+			Request:  RequestMapping{Method: "GET", Path: CommonMatch{Exact: "/first"}},
+			Response: ResponseMapping{StatusCode: 200},
+		}, {
+			Scenario: &ScenarioMapping{Name: "State Expiry Conflict", State: "Second", StateExpiry: &ScenarioExpiry{Requests: 1, ResetTo: "First"}},
+			Request:  RequestMapping{Method: "GET", Path: CommonMatch{Exact: "/first"}},
+			Response: ResponseMapping{StatusCode: 404},
+		}, {
+			Scenario: &ScenarioMapping{Name: "State Expiry Conflict", State: "Second", StateExpiry: &ScenarioExpiry{Requests: 3, ResetTo: "First"}},
+			Request:  RequestMapping{Method: "GET", Path: CommonMatch{Exact: "/second"}},
+			Response: ResponseMapping{StatusCode: 200},
+		},
+	},
 }
 
 var validScenarios = map[string][]Mapping{
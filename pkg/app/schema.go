@@ -0,0 +1,139 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+const (
+	// SchemaFileSuffix marks a file inside the mappings folder as a JSON Schema used by the mappings,
+	// e.g. 'order.schema.json', so it is not loaded as a mapping file.
+	SchemaFileSuffix = ".schema"
+)
+
+// IsSchemaFile reports whether the file is a JSON Schema, based on its name.
+func IsSchemaFile(path string) bool {
+	name := filepath.Base(path)
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+	return strings.HasSuffix(strings.ToLower(name), SchemaFileSuffix)
+}
+
+// SchemaCache holds the compiled body schemas, so a schema file used by several mappings is compiled only once.
+type SchemaCache struct {
+	cache map[string]*jsonschema.Schema
+}
+
+func NewSchemaCache() *SchemaCache {
+	return &SchemaCache{
+		cache: make(map[string]*jsonschema.Schema),
+	}
+}
+
+// Compile compiles the schema of the body match, defined inline or in a file relative to the folder of
+// the mapping file, and sets it to be used when matching.
+func (s *SchemaCache) Compile(body *BodyMatch, filePath string) error {
+	if !body.HasSchema() {
+		return nil
+	}
+
+	url, content := filePath, []byte(body.Schema)
+	key := filePath + "#" + string(content)
+
+	if body.SchemaFile != "" {
+		url = filepath.Join(filepath.Dir(filePath), body.SchemaFile)
+		key = url
+	}
+
+	if schema, ok := s.cache[key]; ok {
+		body.schema = schema
+		return nil
+	}
+
+	if body.SchemaFile != "" {
+		loaded, err := loadFile(url)
+		if err != nil {
+			return errors.Wrap(err, "error loading body schema file")
+		}
+		content = loaded
+		if IsYAMLFile(url) {
+			if content, err = YAMLToJSON(loaded); err != nil {
+				return errors.Wrapf(err, "error decoding body schema file '%s'", url)
+			}
+		}
+	}
+
+	abs, err := filepath.Abs(url)
+	if err != nil {
+		return errors.Wrapf(err, "error resolving body schema path '%s'", url)
+	}
+
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(abs, bytes.NewReader(content)); err != nil {
+		return errors.Wrap(err, "failed to parse body schema")
+	}
+
+	schema, err := compiler.Compile(abs)
+	if err != nil {
+		return errors.Wrap(err, "failed to compile body schema")
+	}
+
+	s.cache[key] = schema
+	body.schema = schema
+	return nil
+}
+
+// SchemaViolations validates the value against the compiled schema of the body match, returning
+// what made it invalid, sorted by location. A value without violations matches the schema.
+func SchemaViolations(body BodyMatch, value string) []string {
+	if body.schema == nil {
+		return nil
+	}
+
+	dec := json.NewDecoder(strings.NewReader(value))
+	dec.UseNumber()
+
+	var parsed any
+	if err := dec.Decode(&parsed); err != nil {
+		return []string{fmt.Sprintf("body is not valid JSON: %s", err)}
+	}
+	if dec.More() {
+		return []string{"body is not valid JSON: unexpected content after the value"}
+	}
+
+	err := body.schema.Validate(parsed)
+	if err == nil {
+		return nil
+	}
+
+	var validationErr *jsonschema.ValidationError
+	if !errors.As(err, &validationErr) {
+		return []string{err.Error()}
+	}
+
+	violations := make([]string, 0)
+	var collect func(*jsonschema.ValidationError)
+	collect = func(e *jsonschema.ValidationError) {
+		if len(e.Causes) == 0 {
+			location := e.InstanceLocation
+			if location == "" {
+				location = "/"
+			}
+			violations = append(violations, fmt.Sprintf("%s: %s", location, e.Message))
+			return
+		}
+		for _, c := range e.Causes {
+			collect(c)
+		}
+	}
+	collect(validationErr)
+	sort.Strings(violations)
+
+	return violations
+}
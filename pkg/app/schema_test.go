@@ -0,0 +1,115 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsSchemaFile(t *testing.T) {
+	assert.True(t, IsSchemaFile("mapping/order.schema.json"))
+	assert.True(t, IsSchemaFile("mapping/order.Schema.yaml"))
+	assert.False(t, IsSchemaFile("mapping/order.json"))
+	assert.False(t, IsSchemaFile("mapping/schema.json"))
+}
+
+func TestSchemaMatch(t *testing.T) {
+	regexCache := NewRegexCache()
+	jsonPathCache := NewJSONPathCache()
+	matcher := NewMatcher(regexCache, jsonPathCache)
+
+	mappings, err := NewLoader(regexCache, jsonPathCache, NewScenarioHandler(matcher)).LoadMappings("testdata/schema/mapping", "")
+	require.NoError(t, err)
+	require.Len(t, mappings["POST"], 2)
+
+	tests := []struct {
+		name       string
+		input      Request
+		wantID     string
+		wantMatch  bool
+		wantReason string
+	}{
+		{
+			name:      "Should match a body valid against the schema file",
+			input:     Request{Method: "POST", Path: "/orders", Body: `{"sku": "123", "quantity": 2}`},
+			wantID:    "post-orders",
+			wantMatch: true,
+		},
+		{
+			name:       "Should not match a body missing required properties",
+			input:      Request{Method: "POST", Path: "/orders", Body: `{"sku": "123"}`},
+			wantID:     "post-orders",
+			wantReason: "/: missing properties: 'quantity'",
+		},
+		{
+			name:       "Should not match a body with invalid values",
+			input:      Request{Method: "POST", Path: "/orders", Body: `{"sku": 123, "quantity": 0}`},
+			wantID:     "post-orders",
+			wantReason: "/quantity: must be >= 1 but found 0; /sku: expected string, but got number",
+		},
+		{
+			name:       "Should not match a body that is not JSON",
+			input:      Request{Method: "POST", Path: "/orders", Body: `sku=123`},
+			wantID:     "post-orders",
+			wantReason: "body is not valid JSON: invalid character 's' looking for beginning of value",
+		},
+		{
+			name:      "Should match a body valid against the inline schema",
+			input:     Request{Method: "POST", Path: "/users", Body: `{"name": "Mantis"}`},
+			wantID:    "post-users",
+			wantMatch: true,
+		},
+		{
+			name:       "Should not match a body invalid against the inline schema",
+			input:      Request{Method: "POST", Path: "/users", Body: `{"name": ""}`},
+			wantID:     "post-users",
+			wantReason: "/name: length must be >= 1, but got 0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mapping, matched, partial := matcher.Match(tt.input, mappings, nil)
+			assert.Equal(t, tt.wantMatch, matched)
+			assert.Equal(t, !tt.wantMatch, partial)
+			assert.Equal(t, tt.wantID, mapping.ID)
+
+			diff := matcher.Diff(tt.input, mapping)
+			if tt.wantMatch {
+				assert.Equal(t, diff.MaxScore, diff.Score)
+				assert.Empty(t, diff.Failed())
+				return
+			}
+
+			require.Len(t, diff.Failed(), 1)
+			assert.Equal(t, SchemaMatcher, diff.Failed()[0].Matcher)
+			assert.Equal(t, tt.wantReason, diff.Failed()[0].Reason)
+		})
+	}
+}
+
+func TestSchemaCache(t *testing.T) {
+	t.Run("Should compile a schema file once", func(t *testing.T) {
+		cache := NewSchemaCache()
+		a := BodyMatch{SchemaFile: "order.schema.json"}
+		b := BodyMatch{SchemaFile: "order.schema.json"}
+
+		require.NoError(t, cache.Compile(&a, "testdata/schema/mapping/a.json"))
+		require.NoError(t, cache.Compile(&b, "testdata/schema/mapping/b.json"))
+
+		assert.NotNil(t, a.schema)
+		assert.Same(t, a.schema, b.schema)
+	})
+
+	t.Run("Should fail for missing schema files", func(t *testing.T) {
+		body := BodyMatch{SchemaFile: "missing.schema.json"}
+		err := NewSchemaCache().Compile(&body, "testdata/schema/mapping/a.json")
+		assert.EqualError(t, err, "error loading body schema file: file 'testdata/schema/mapping/missing.schema.json' not found")
+	})
+
+	t.Run("Should fail for invalid schemas", func(t *testing.T) {
+		_, err := NewLoader(NewRegexCache(), NewJSONPathCache(), NewScenarioHandler(nil)).LoadMappings("testdata/schema/invalid", "")
+		assert.ErrorContains(t, err, "error processing file [ testdata/schema/invalid/post_orders.json ]: error adding mapping from: failed to compile body schema")
+	})
+}
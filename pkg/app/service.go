@@ -1,20 +1,35 @@
 package app
 
 import (
+	"context"
 	"net/http"
+	"sort"
+	"time"
+
+	"github.com/americanas-go/config"
 
 	"github.com/ohler55/ojg/oj"
 )
 
 const (
 	NoMappingFoundMessage = "No mapping found for the request"
+
+	MappingIDHeader = "X-Mapping-Id"
 )
 
 type Service struct {
-	matcher         *Matcher
-	scenarioHandler *ScenarioHandler
-	delayer         Delayer
-	mappings        Mappings
+	matcher            *Matcher
+	scenarioHandler    *ScenarioHandler
+	delayer            Delayer
+	selector           *ResponseSelector
+	callbacks          *CallbackDispatcher
+	store              *Store
+	mappings           Mappings
+	journal            *Journal
+	metrics            *Metrics
+	tracing            *Tracing
+	nearMissCandidates int
+	contract           ContractMode
 }
 
 type MatchResult struct {
@@ -23,6 +38,12 @@ type MatchResult struct {
 	Body        any
 	Matched     bool
 	MappingFile string
+	Stream      *EventStream
+	WebSocket   *WebSocketMapping
+	MappingName string
+	Scenario    *ScenarioTransition
+	Transitions []ScenarioTransition
+	Delay       time.Duration
 }
 
 func NewMatchResult(mapping *Mapping, r Request, matched bool, partial bool) MatchResult {
@@ -36,6 +57,9 @@ func NewMatchResult(mapping *Mapping, r Request, matched bool, partial bool) Mat
 		result.StatusCode = http.StatusNotFound
 		result.Headers["Content-type"] = "application/json"
 		result.Headers["X-Mapping-File"] = mapping.FilePath
+		if mapping.ID != "" {
+			result.Headers[MappingIDHeader] = mapping.ID
+		}
 		return result
 	}
 
@@ -49,12 +73,18 @@ func NewMatchResult(mapping *Mapping, r Request, matched bool, partial bool) Mat
 	if mapping.Response.Body != "" {
 		result.Body = mapping.Response.Body
 	}
+	result.Stream = mapping.Response.Stream
+	result.WebSocket = mapping.WebSocket
 	result.StatusCode = mapping.Response.StatusCode
-	result.Headers = mapping.Response.Headers
-	if result.Headers == nil {
-		result.Headers = make(map[string]string)
+	result.Headers = make(map[string]string, len(mapping.Response.Headers)+2)
+	for k, v := range mapping.Response.Headers {
+		result.Headers[k] = v
 	}
 	result.Headers["X-Mapping-File"] = mapping.FilePath
+	if mapping.ID != "" {
+		result.Headers[MappingIDHeader] = mapping.ID
+	}
+	result.MappingName = mapping.Name
 
 	return result
 }
@@ -67,39 +97,214 @@ type NotFoundResponse struct {
 	Message        string          `json:"message"`
 	Request        Request         `json:"request"`
 	ClosestMapping *RequestMapping `json:"closestMapping,omitempty"`
+	Candidates     []MatchDiff     `json:"candidates,omitempty"`
 }
 
-func NewService(mappings Mappings, matcher *Matcher, scenarioHandler *ScenarioHandler, delayer Delayer) *Service {
+func NewService(mappings Mappings, matcher *Matcher, scenarioHandler *ScenarioHandler, delayer Delayer, selector *ResponseSelector, callbacks *CallbackDispatcher, store *Store, journal *Journal, metrics *Metrics, tracing *Tracing) *Service {
+	candidates := config.Int("matcher.nearMiss.candidates")
+	if candidates <= 0 {
+		candidates = DefaultNearMissCandidates
+	}
+
+	for _, tag := range config.Strings("matcher.disabledTags") {
+		matcher.Toggles().SetTag(tag, false)
+	}
+
 	return &Service{
-		matcher:         matcher,
-		scenarioHandler: scenarioHandler,
-		delayer:         delayer,
-		mappings:        mappings,
+		matcher:            matcher,
+		scenarioHandler:    scenarioHandler,
+		delayer:            delayer,
+		selector:           selector,
+		callbacks:          callbacks,
+		store:              store,
+		mappings:           mappings,
+		journal:            journal,
+		metrics:            metrics,
+		tracing:            tracing,
+		nearMissCandidates: candidates,
+		contract:           NewContractMode(config.Bool("matcher.contract.enabled"), config.Int("matcher.contract.statusCode")),
 	}
 }
 
-func (s *Service) MatchRequest(r Request) MatchResult {
+func (s *Service) MatchRequest(ctx context.Context, r Request) MatchResult {
 	var mapping Mapping
 	var matched, partial bool
 
+	start := time.Now()
+
+	_, span := s.tracing.Start(ctx, "mantis.match.scenario")
 	mapping, matched, partial = s.scenarioHandler.MatchScenario(r)
+	span.SetAttributes(matchAttributes(mapping, matched)...)
+	span.End()
+
+	if matched && mapping.Scenario.NewState != "" {
+		s.metrics.ObserveScenarioTransition(mapping.Scenario)
+	}
 	if !matched {
+		_, span = s.tracing.Start(ctx, "mantis.match")
 		mapping, matched, partial = s.matcher.Match(r, s.mappings, nil)
+		span.SetAttributes(matchAttributes(mapping, matched)...)
+		span.End()
+	}
+	// a partial match only names the closest mapping, the request is counted as unmatched
+	mappingFile := ""
+	if matched {
+		mappingFile = mapping.FilePath
+	}
+	s.metrics.ObserveRequest(r, matched, mappingFile, time.Since(start))
+
+	if matched {
+		mapping.Response = s.selector.Select(&mapping)
 	}
 
 	result := NewMatchResult(&mapping, r, matched, partial)
 
+	if partial {
+		if candidate, diff, ok := s.matcher.ContractCandidate(r, s.mappings, s.contract); ok {
+			if violation, ok := NewContractViolationResult(&candidate, diff, s.contract); ok {
+				result = violation
+			}
+		}
+	}
+
+	if matched && mapping.Store != nil {
+		s.store.Apply(mapping.Store, r, &result)
+	}
+
+	if matched && mapping.Scenario != nil {
+		result.Scenario = &ScenarioTransition{Name: mapping.Scenario.Name, From: mapping.Scenario.State, To: mapping.Scenario.NewState}
+	}
+
+	if matched && len(mapping.Transitions) > 0 {
+		result.Transitions = s.scenarioHandler.Trigger(r, mapping.Transitions)
+		for _, t := range result.Transitions {
+			s.metrics.ObserveScenarioTransition(&ScenarioMapping{Name: t.Name, State: t.From, NewState: t.To})
+		}
+	}
+
 	if matched {
+		result.Delay = time.Duration(mapping.Response.ResponseDelay.Fixed.Duration)
+		_, span = s.tracing.Start(ctx, "mantis.delay")
+		delayStart := time.Now()
 		s.delayer.Apply(&mapping.Response.ResponseDelay)
+		s.metrics.ObserveDelay(time.Since(delayStart))
+		span.End()
+		s.callbacks.Dispatch(mapping, r)
+	} else if notFound, ok := result.Body.(NotFoundResponse); ok {
+		notFound.Candidates = s.NearMisses(r)
+		result.Body = notFound
 	}
 
+	s.journal.Record(JournalEntry{
+		Request:     r,
+		Matched:     matched,
+		StatusCode:  result.StatusCode,
+		MappingFile: mapping.FilePath,
+	})
+
 	return result
 }
 
+// NearMisses returns the mappings, including scenario mappings, that came closer to matching the request.
+func (s *Service) NearMisses(r Request) []MatchDiff {
+	return nearMisses(s.matcher, s.scenarioHandler, s.mappings, r, s.nearMissCandidates)
+}
+
+func nearMisses(matcher *Matcher, scenarioHandler *ScenarioHandler, mappings Mappings, r Request, n int) []MatchDiff {
+	diffs := matcher.Closest(r, mappings, nil, 0)
+	diffs = append(diffs, matcher.Closest(r, scenarioHandler.scenarioMappings, scenarioHandler.CurrentStates(r), 0)...)
+	SortDiffs(diffs)
+
+	if len(diffs) > n {
+		diffs = diffs[:n]
+	}
+
+	return diffs
+}
+
+// MappingSummary describes a loaded mapping and whether it is enabled.
+type MappingSummary struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Method      string   `json:"method"`
+	MappingFile string   `json:"mappingFile"`
+	Scenario    string   `json:"scenario,omitempty"`
+	Enabled     bool     `json:"enabled"`
+}
+
+// Mappings returns the loaded mappings, including scenario mappings, sorted by file.
+// When tag is not empty, only mappings with the tag are returned.
+func (s *Service) Mappings(tag string) []MappingSummary {
+	summaries := make([]MappingSummary, 0)
+	for _, mappings := range []Mappings{s.mappings, s.scenarioHandler.scenarioMappings} {
+		for _, methodMappings := range mappings {
+			for _, m := range methodMappings {
+				if tag != "" && !m.HasTag(tag) {
+					continue
+				}
+
+				summary := MappingSummary{
+					ID:          m.ID,
+					Name:        m.Name,
+					Tags:        m.Tags,
+					Method:      m.Request.Method,
+					MappingFile: m.FilePath,
+					Enabled:     s.matcher.Toggles().Enabled(&m),
+				}
+				if m.Scenario != nil {
+					summary.Scenario = m.Scenario.Name
+				}
+				summaries = append(summaries, summary)
+			}
+		}
+	}
+
+	sort.SliceStable(summaries, func(i, j int) bool {
+		if summaries[i].MappingFile != summaries[j].MappingFile {
+			return summaries[i].MappingFile < summaries[j].MappingFile
+		}
+		return summaries[i].ID < summaries[j].ID
+	})
+
+	return summaries
+}
+
+// ScenarioGraphs returns the graph of every scenario, with its current state, sorted by name.
+func (s *Service) ScenarioGraphs() []ScenarioGraph {
+	return s.scenarioHandler.Graphs()
+}
+
+// ScenarioGraph returns the graph of the scenario, or false if there is no such scenario.
+func (s *Service) ScenarioGraph(name string) (ScenarioGraph, bool) {
+	return s.scenarioHandler.Graph(name)
+}
+
+// SetMappingEnabled enables or disables the mapping with the ID, returning false if there is no such mapping.
+func (s *Service) SetMappingEnabled(id string, enabled bool) bool {
+	for _, mappings := range []Mappings{s.mappings, s.scenarioHandler.scenarioMappings} {
+		for _, methodMappings := range mappings {
+			for _, m := range methodMappings {
+				if m.ID == id {
+					s.matcher.Toggles().SetMapping(id, enabled)
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// SetTagEnabled enables or disables all mappings with the tag.
+func (s *Service) SetTagEnabled(tag string, enabled bool) {
+	s.matcher.Toggles().SetTag(tag, enabled)
+}
+
 func buildNotFoundResponse(r Request, mapping *RequestMapping) NotFoundResponse {
 	return NotFoundResponse{
 		Message:        NoMappingFoundMessage,
 		Request:        r,
 		ClosestMapping: mapping,
+		Candidates:     make([]MatchDiff, 0),
 	}
 }
@@ -1,10 +1,12 @@
 package app
 
 import (
+	"context"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 type mockDelayer struct {
@@ -39,6 +41,14 @@ func TestService(t *testing.T) {
 				Cost:     0,
 				FilePath: "file_2",
 			},
+			{
+				ID:       "get-named",
+				Name:     "Named mapping",
+				Request:  RequestMapping{Method: "GET", Path: CommonMatch{Exact: "/named"}},
+				Response: ResponseMapping{StatusCode: 200},
+				MaxScore: 1,
+				FilePath: "file_3",
+			},
 		},
 	}
 
@@ -54,10 +64,16 @@ func TestService(t *testing.T) {
 			wantResult: MatchResult{StatusCode: 204, Matched: true, Headers: map[string]string{"X-Mapping-File": "file_2"}},
 			wantDelay:  false,
 		},
+		{
+			name:       "Should match request and return mapping id",
+			request:    Request{Method: "GET", Path: "/named"},
+			wantResult: MatchResult{StatusCode: 200, Matched: true, Headers: map[string]string{"X-Mapping-File": "file_3", "X-Mapping-Id": "get-named"}, MappingName: "Named mapping"},
+			wantDelay:  false,
+		},
 		{
 			name:       "Should match request with fixed delay",
 			request:    Request{Method: "GET", Path: "/fixed/delay"},
-			wantResult: MatchResult{StatusCode: 204, Matched: true, Headers: map[string]string{"X-Mapping-File": "file_1"}},
+			wantResult: MatchResult{StatusCode: 204, Matched: true, Headers: map[string]string{"X-Mapping-File": "file_1"}, Delay: 10 * time.Second},
 			wantDelay:  true,
 		},
 	}
@@ -66,13 +82,114 @@ func TestService(t *testing.T) {
 
 	for _, tt := range tests {
 		delayer := mockDelayer{}
-		service := NewService(mappings, matcher, NewScenarioHandler(matcher), &delayer)
+		service := NewService(mappings, matcher, NewScenarioHandler(matcher), &delayer, NewResponseSelector(), NewCallbackDispatcher(&delayer), NewStore(), NewJournal(), NewMetrics(mappings, nil), NewNoopTracing())
 
 		t.Run(tt.name, func(t *testing.T) {
-			res := service.MatchRequest(tt.request)
+			res := service.MatchRequest(context.Background(), tt.request)
 			assert.Equal(t, tt.wantResult, res)
 			assert.Equal(t, tt.wantDelay, delayer.FixedCalled)
 		})
 	}
 
 }
+
+func TestServiceNearMisses(t *testing.T) {
+	mappings := getMappings()
+	matcher := newTestMatcher(mappings)
+	service := NewService(mappings, matcher, NewScenarioHandler(matcher), &mockDelayer{}, NewResponseSelector(), NewCallbackDispatcher(&mockDelayer{}), NewStore(), NewJournal(), NewMetrics(mappings, nil), NewNoopTracing())
+
+	request := Request{Method: "POST", Path: "/order", Headers: map[string]string{"authorization": "Bearer NotMe"}, Body: `{"cart": "777"}`}
+	res := service.MatchRequest(context.Background(), request)
+
+	assert.False(t, res.Matched)
+	notFound, ok := res.Body.(NotFoundResponse)
+	require.True(t, ok)
+	require.Len(t, notFound.Candidates, 2)
+	assert.Equal(t, "file_9", notFound.Candidates[0].MappingFile)
+	assert.Equal(t, "file_8", notFound.Candidates[1].MappingFile)
+	assert.Equal(t, []FieldDiff{
+		{Field: "headers.authorization", Matcher: ExactMatcher, Expected: "Bearer ItsMe", Actual: "Bearer NotMe", Reason: "expected 'Bearer ItsMe' but got 'Bearer NotMe'"},
+		{Field: "body", Matcher: ExactMatcher, Expected: `{"cart": "555"}`, Actual: `{"cart": "777"}`, Reason: `expected '{"cart": "555"}' but got '{"cart": "777"}'`},
+	}, notFound.Candidates[1].Failed())
+}
+
+func TestServiceKeepsMappingHeaders(t *testing.T) {
+	mappings := Mappings{
+		"GET": []Mapping{
+			{
+				ID:       "get-headers",
+				Request:  RequestMapping{Method: "GET", Path: CommonMatch{Exact: "/headers"}},
+				Response: ResponseMapping{StatusCode: 200, Headers: map[string]string{"Content-Type": "application/json"}},
+				MaxScore: 1,
+				FilePath: "file_1",
+			},
+		},
+	}
+	matcher := newTestMatcher(mappings)
+	service := NewService(mappings, matcher, NewScenarioHandler(matcher), &mockDelayer{}, NewResponseSelector(), NewCallbackDispatcher(&mockDelayer{}), NewStore(), NewJournal(), NewMetrics(mappings, nil), NewNoopTracing())
+
+	res := service.MatchRequest(context.Background(), Request{Method: "GET", Path: "/headers"})
+
+	assert.Equal(t, map[string]string{"Content-Type": "application/json", "X-Mapping-File": "file_1", "X-Mapping-Id": "get-headers"}, res.Headers)
+	assert.Equal(t, map[string]string{"Content-Type": "application/json"}, mappings["GET"][0].Response.Headers)
+}
+
+func TestServiceScenarioTransition(t *testing.T) {
+	matcher := NewMatcher(NewRegexCache(), NewJSONPathCache())
+	scenarioHandler := NewScenarioHandler(matcher)
+	scenarioHandler.AddScenario(Mapping{
+		Scenario: &ScenarioMapping{Name: "cart", StartingState: true, State: "empty", NewState: "full"},
+		Request:  RequestMapping{Method: "POST", Path: CommonMatch{Exact: "/cart"}},
+		Response: ResponseMapping{StatusCode: 201},
+		MaxScore: 1,
+		FilePath: "cart_empty.json",
+	})
+	mappings := make(Mappings)
+	service := NewService(mappings, matcher, scenarioHandler, &mockDelayer{}, NewResponseSelector(), NewCallbackDispatcher(&mockDelayer{}), NewStore(), NewJournal(), NewMetrics(mappings, nil), NewNoopTracing())
+
+	res := service.MatchRequest(context.Background(), Request{Method: "POST", Path: "/cart"})
+
+	assert.Equal(t, &ScenarioTransition{Name: "cart", From: "empty", To: "full"}, res.Scenario)
+	assert.Equal(t, "cart_empty.json", res.Headers["X-Mapping-File"])
+}
+
+func TestServiceScenarioTrigger(t *testing.T) {
+	matcher := NewMatcher(NewRegexCache(), NewJSONPathCache())
+	scenarioHandler := NewScenarioHandler(matcher)
+	for _, m := range []Mapping{
+		{
+			Scenario: &ScenarioMapping{Name: "session", StartingState: true, State: "logged out", NewState: "logged in"},
+			Request:  RequestMapping{Method: "POST", Path: CommonMatch{Exact: "/login"}},
+			Response: ResponseMapping{StatusCode: 200},
+			MaxScore: 1,
+		}, {
+			Scenario: &ScenarioMapping{Name: "session", State: "logged in"},
+			Request:  RequestMapping{Method: "GET", Path: CommonMatch{Exact: "/me"}},
+			Response: ResponseMapping{StatusCode: 200},
+			MaxScore: 1,
+		},
+	} {
+		scenarioHandler.AddScenario(m)
+	}
+
+	mappings := make(Mappings)
+	logout := Mapping{
+		Request:     RequestMapping{Method: "POST", Path: CommonMatch{Exact: "/logout"}},
+		Response:    ResponseMapping{StatusCode: 204},
+		Transitions: []ScenarioTrigger{{Scenario: "session", NewState: "logged out"}},
+		MaxScore:    1,
+		FilePath:    "logout.json",
+	}
+	require.NoError(t, mappings.Put(logout))
+	scenarioHandler.AddTriggers(logout)
+	require.NoError(t, scenarioHandler.ValidateScenarioStates())
+
+	service := NewService(mappings, matcher, scenarioHandler, &mockDelayer{}, NewResponseSelector(), NewCallbackDispatcher(&mockDelayer{}), NewStore(), NewJournal(), NewMetrics(mappings, nil), NewNoopTracing())
+
+	service.MatchRequest(context.Background(), Request{Method: "POST", Path: "/login"})
+	assert.True(t, service.MatchRequest(context.Background(), Request{Method: "GET", Path: "/me"}).Matched)
+
+	res := service.MatchRequest(context.Background(), Request{Method: "POST", Path: "/logout"})
+	assert.Equal(t, []ScenarioTransition{{Name: "session", From: "logged in", To: "logged out"}}, res.Transitions)
+	assert.False(t, service.MatchRequest(context.Background(), Request{Method: "GET", Path: "/me"}).Matched)
+}
@@ -0,0 +1,344 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ohler55/ojg/jp"
+	"github.com/ohler55/ojg/oj"
+	"github.com/pkg/errors"
+)
+
+const (
+	StoreList   = "list"
+	StoreGet    = "get"
+	StorePut    = "put"
+	StoreDelete = "delete"
+
+	StoreNameMessage      = "Store name is required"
+	StoreOperationMessage = "Store operation must be one of 'list', 'get', 'put' or 'delete'"
+	StoreKeyMessage       = "Store key must define one of 'pathPattern' or 'jsonPath'"
+	StoreListKeyMessage   = "Store key is not used by the 'list' operation"
+
+	StoreNotFoundMessage   = "Resource '%s' not found in store '%s'"
+	StoreMissingKeyMessage = "Could not read the resource key from the request"
+	StoreInvalidMessage    = "Request body must be a JSON document"
+)
+
+// StoreMapping makes the mapping read from or write to a named in-memory store of JSON resources,
+// emulating a stateful API: 'put' saves the request body, 'get' and 'delete' work on a single resource
+// and 'list' returns all of them, in the order they were first saved.
+//
+// The resource, or the list of resources, is the response body unless the mapping defines one.
+type StoreMapping struct {
+	Name      string   `json:"name"`
+	Operation string   `json:"operation"`
+	Key       StoreKey `json:"key,omitempty"`
+}
+
+// StoreKey defines where the key of the resource comes from: the first capture group of a pattern
+// matched against the request path, or a JSONPath expression evaluated against the request body.
+type StoreKey struct {
+	PathPattern string `json:"pathPattern,omitempty"`
+	JsonPath    string `json:"jsonPath,omitempty"`
+
+	pattern *regexp.Regexp
+	expr    jp.Expr
+}
+
+func (s *StoreMapping) Validate() ValidationErrors {
+	errs := make(ValidationErrors, 0)
+	if s.Name == "" {
+		errs = append(errs, ValidationError{"Store.Name", StoreNameMessage})
+	}
+
+	hasKey := s.Key.PathPattern != "" || s.Key.JsonPath != ""
+	bothKeys := s.Key.PathPattern != "" && s.Key.JsonPath != ""
+
+	switch s.Operation {
+	case StoreList:
+		if hasKey {
+			errs = append(errs, ValidationError{"Store.Key", StoreListKeyMessage})
+		}
+	case StoreGet, StorePut, StoreDelete:
+		if !hasKey || bothKeys {
+			errs = append(errs, ValidationError{"Store.Key", StoreKeyMessage})
+		}
+	default:
+		errs = append(errs, ValidationError{"Store.Operation", StoreOperationMessage})
+	}
+
+	return errs
+}
+
+// compile parses the key pattern and expression, so they are ready to be used when the mapping is matched.
+func (k *StoreKey) compile() error {
+	if k.PathPattern != "" {
+		pattern, err := regexp.Compile(k.PathPattern)
+		if err != nil {
+			return errors.Wrapf(err, "failed to compile store key regex with pattern: %s", k.PathPattern)
+		}
+		if pattern.NumSubexp() == 0 {
+			return errors.Errorf("store key pattern must have a capture group: %s", k.PathPattern)
+		}
+		k.pattern = pattern
+	}
+
+	if k.JsonPath != "" {
+		expr, err := jp.ParseString(k.JsonPath)
+		if err != nil {
+			return errors.Wrapf(err, "failed to parse store key jsonpath expression: %s", k.JsonPath)
+		}
+		k.expr = expr
+	}
+
+	return nil
+}
+
+// value returns the key of the resource targeted by the request.
+func (k StoreKey) value(r Request) (string, bool) {
+	if k.pattern != nil {
+		groups := k.pattern.FindStringSubmatch(r.Path)
+		if len(groups) < 2 || groups[1] == "" {
+			return "", false
+		}
+		return groups[1], true
+	}
+
+	if k.expr != nil {
+		parsed, err := oj.ParseString(r.Body)
+		if err != nil {
+			return "", false
+		}
+		values := k.expr.Get(parsed)
+		if len(values) == 0 {
+			return "", false
+		}
+		if s, ok := values[0].(string); ok {
+			return s, s != ""
+		}
+		return oj.JSON(values[0]), true
+	}
+
+	return "", false
+}
+
+type resources struct {
+	keys   []string
+	values map[string]string
+}
+
+// StoreSummary describes a store and how many resources it holds.
+type StoreSummary struct {
+	Name      string `json:"name"`
+	Resources int    `json:"resources"`
+}
+
+// Store keeps the resources written by the mappings with a store, in memory, by store name.
+type Store struct {
+	mu     sync.RWMutex
+	stores map[string]*resources
+}
+
+func NewStore() *Store {
+	return &Store{
+		stores: make(map[string]*resources),
+	}
+}
+
+// Apply runs the store operation of the mapping for the request, updating the result of the match
+// with the resource read or written, or with an error status when the operation can't be done.
+func (s *Store) Apply(m *StoreMapping, r Request, result *MatchResult) {
+	var body string
+	var status int
+
+	switch m.Operation {
+	case StoreList:
+		body = s.list(m.Name)
+	case StoreGet, StoreDelete, StorePut:
+		key, ok := m.Key.value(r)
+		if !ok {
+			status, body = http.StatusBadRequest, storeMessage(StoreMissingKeyMessage)
+			break
+		}
+
+		if m.Operation == StorePut {
+			if !json.Valid([]byte(r.Body)) {
+				status, body = http.StatusBadRequest, storeMessage(StoreInvalidMessage)
+				break
+			}
+			s.put(m.Name, key, r.Body)
+			body = r.Body
+			break
+		}
+
+		get := s.get
+		if m.Operation == StoreDelete {
+			get = s.remove
+		}
+
+		value, found := get(m.Name, key)
+		if !found {
+			status, body = http.StatusNotFound, storeMessage(fmt.Sprintf(StoreNotFoundMessage, key, m.Name))
+			break
+		}
+		body = value
+	}
+
+	if status != 0 {
+		result.StatusCode = status
+		result.Body = body
+		result.Headers = withJSONContentType(result.Headers)
+		return
+	}
+
+	if result.Body == nil {
+		result.Body = body
+		result.Headers = withJSONContentType(result.Headers)
+	}
+}
+
+func (s *Store) list(name string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	store, ok := s.stores[name]
+	if !ok {
+		return "[]"
+	}
+
+	values := make([]string, 0, len(store.keys))
+	for _, k := range store.keys {
+		values = append(values, store.values[k])
+	}
+	return "[" + strings.Join(values, ",") + "]"
+}
+
+func (s *Store) get(name, key string) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	store, ok := s.stores[name]
+	if !ok {
+		return "", false
+	}
+
+	value, ok := store.values[key]
+	return value, ok
+}
+
+// remove deletes the resource with the key, returning it.
+func (s *Store) remove(name, key string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	store, ok := s.stores[name]
+	if !ok {
+		return "", false
+	}
+
+	value, ok := store.values[key]
+	if !ok {
+		return "", false
+	}
+
+	delete(store.values, key)
+	for i, k := range store.keys {
+		if k == key {
+			store.keys = append(store.keys[:i], store.keys[i+1:]...)
+			break
+		}
+	}
+
+	return value, true
+}
+
+func (s *Store) put(name, key, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	store, ok := s.stores[name]
+	if !ok {
+		store = &resources{values: make(map[string]string)}
+		s.stores[name] = store
+	}
+
+	if _, exists := store.values[key]; !exists {
+		store.keys = append(store.keys, key)
+	}
+	store.values[key] = value
+}
+
+// Stores lists the stores holding resources, sorted by name.
+func (s *Store) Stores() []StoreSummary {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	summaries := make([]StoreSummary, 0, len(s.stores))
+	for name, store := range s.stores {
+		summaries = append(summaries, StoreSummary{Name: name, Resources: len(store.keys)})
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].Name < summaries[j].Name
+	})
+
+	return summaries
+}
+
+// Resources returns the resources of the store by key, or false if the store holds none.
+func (s *Store) Resources(name string) (map[string]any, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	store, ok := s.stores[name]
+	if !ok {
+		return nil, false
+	}
+
+	values := make(map[string]any, len(store.values))
+	for k, v := range store.values {
+		values[k], _ = oj.ParseString(v)
+	}
+	return values, true
+}
+
+// Reset removes every resource of the store.
+func (s *Store) Reset(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.stores, name)
+}
+
+// ResetAll removes the resources of every store.
+func (s *Store) ResetAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stores = make(map[string]*resources)
+}
+
+func storeMessage(message string) string {
+	return oj.JSON(map[string]string{"message": message})
+}
+
+// withJSONContentType returns a copy of the headers with a JSON content type, unless one is already set.
+func withJSONContentType(headers map[string]string) map[string]string {
+	copied := make(map[string]string, len(headers)+1)
+	hasContentType := false
+	for k, v := range headers {
+		copied[k] = v
+		if strings.EqualFold(k, "content-type") {
+			hasContentType = true
+		}
+	}
+	if !hasContentType {
+		copied["Content-type"] = "application/json"
+	}
+	return copied
+}
@@ -0,0 +1,269 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newStoreMappings(t *testing.T) Mappings {
+	t.Helper()
+	byID := CommonMatch{Patterns: []string{"^/users/[^/]+$"}}
+	idKey := StoreKey{PathPattern: "^/users/([^/]+)$"}
+
+	mappings := []Mapping{
+		{
+			ID:       "create-user",
+			Request:  RequestMapping{Method: "POST", Path: CommonMatch{Exact: "/users"}},
+			Response: ResponseMapping{StatusCode: 201},
+			Store:    &StoreMapping{Name: "users", Operation: StorePut, Key: StoreKey{JsonPath: "$.id"}},
+		},
+		{
+			ID:       "list-users",
+			Request:  RequestMapping{Method: "GET", Path: CommonMatch{Exact: "/users"}},
+			Response: ResponseMapping{StatusCode: 200},
+			Store:    &StoreMapping{Name: "users", Operation: StoreList},
+		},
+		{
+			ID:       "get-user",
+			Request:  RequestMapping{Method: "GET", Path: byID},
+			Response: ResponseMapping{StatusCode: 200, Headers: map[string]string{"content-type": "application/vnd.user+json"}},
+			Store:    &StoreMapping{Name: "users", Operation: StoreGet, Key: idKey},
+		},
+		{
+			ID:       "update-user",
+			Request:  RequestMapping{Method: "PUT", Path: byID},
+			Response: ResponseMapping{StatusCode: 200},
+			Store:    &StoreMapping{Name: "users", Operation: StorePut, Key: idKey},
+		},
+		{
+			ID:       "delete-user",
+			Request:  RequestMapping{Method: "DELETE", Path: byID},
+			Response: ResponseMapping{StatusCode: 204, Body: ""},
+			Store:    &StoreMapping{Name: "users", Operation: StoreDelete, Key: idKey},
+		},
+		{
+			ID:       "create-order",
+			Request:  RequestMapping{Method: "POST", Path: CommonMatch{Exact: "/orders"}},
+			Response: ResponseMapping{StatusCode: 202, Body: `{"status": "accepted"}`},
+			Store:    &StoreMapping{Name: "orders", Operation: StorePut, Key: StoreKey{JsonPath: "$.number"}},
+		},
+	}
+
+	result := make(Mappings)
+	for _, m := range mappings {
+		require.NoError(t, m.Store.Key.compile())
+		m.CalcMaxScoreAndCost()
+		require.NoError(t, result.Put(m))
+	}
+	return result
+}
+
+func newStoreService(t *testing.T, store *Store) *Service {
+	t.Helper()
+	mappings := newStoreMappings(t)
+	matcher := newTestMatcher(mappings)
+	return NewService(mappings, matcher, NewScenarioHandler(matcher), &mockDelayer{}, NewResponseSelector(), NewCallbackDispatcher(&mockDelayer{}), store, NewJournal(), NewMetrics(mappings, nil), NewNoopTracing())
+}
+
+func TestStore(t *testing.T) {
+	service := newStoreService(t, NewStore())
+
+	steps := []struct {
+		name        string
+		request     Request
+		wantStatus  int
+		wantBody    any
+		contentType string
+	}{
+		{
+			name:        "Should list an empty store",
+			request:     Request{Method: "GET", Path: "/users"},
+			wantStatus:  200,
+			wantBody:    `[]`,
+			contentType: "application/json",
+		},
+		{
+			name:        "Should create a resource keyed by the body",
+			request:     Request{Method: "POST", Path: "/users", Body: `{"id": "1", "name": "Ann"}`},
+			wantStatus:  201,
+			wantBody:    `{"id": "1", "name": "Ann"}`,
+			contentType: "application/json",
+		},
+		{
+			name:       "Should create a resource keyed by a number",
+			request:    Request{Method: "POST", Path: "/users", Body: `{"id": 2, "name": "Bob"}`},
+			wantStatus: 201,
+			wantBody:   `{"id": 2, "name": "Bob"}`,
+		},
+		{
+			name:        "Should get a resource keyed by the path, keeping the response headers",
+			request:     Request{Method: "GET", Path: "/users/2"},
+			wantStatus:  200,
+			wantBody:    `{"id": 2, "name": "Bob"}`,
+			contentType: "application/vnd.user+json",
+		},
+		{
+			name:       "Should replace a resource",
+			request:    Request{Method: "PUT", Path: "/users/1", Body: `{"id": "1", "name": "Anne"}`},
+			wantStatus: 200,
+			wantBody:   `{"id": "1", "name": "Anne"}`,
+		},
+		{
+			name:       "Should list the resources in the order they were created",
+			request:    Request{Method: "GET", Path: "/users"},
+			wantStatus: 200,
+			wantBody:   `[{"id": "1", "name": "Anne"},{"id": 2, "name": "Bob"}]`,
+		},
+		{
+			name:       "Should delete a resource",
+			request:    Request{Method: "DELETE", Path: "/users/1"},
+			wantStatus: 204,
+			wantBody:   `{"id": "1", "name": "Anne"}`,
+		},
+		{
+			name:       "Should not find deleted resources",
+			request:    Request{Method: "GET", Path: "/users/1"},
+			wantStatus: 404,
+			wantBody:   `{"message":"Resource '1' not found in store 'users'"}`,
+		},
+		{
+			name:       "Should not delete missing resources",
+			request:    Request{Method: "DELETE", Path: "/users/1"},
+			wantStatus: 404,
+			wantBody:   `{"message":"Resource '1' not found in store 'users'"}`,
+		},
+		{
+			name:       "Should reject bodies without the key",
+			request:    Request{Method: "POST", Path: "/users", Body: `{"name": "Cid"}`},
+			wantStatus: 400,
+			wantBody:   `{"message":"Could not read the resource key from the request"}`,
+		},
+		{
+			name:       "Should reject bodies that are not JSON",
+			request:    Request{Method: "PUT", Path: "/users/3", Body: `name=Cid`},
+			wantStatus: 400,
+			wantBody:   `{"message":"Request body must be a JSON document"}`,
+		},
+		{
+			name:       "Should keep the response body of the mapping",
+			request:    Request{Method: "POST", Path: "/orders", Body: `{"number": 10}`},
+			wantStatus: 202,
+			wantBody:   `{"status": "accepted"}`,
+		},
+		{
+			name:       "Should keep stores apart",
+			request:    Request{Method: "GET", Path: "/users"},
+			wantStatus: 200,
+			wantBody:   `[{"id": 2, "name": "Bob"}]`,
+		},
+	}
+
+	for _, tt := range steps {
+		t.Run(tt.name, func(t *testing.T) {
+			res := service.MatchRequest(context.Background(), tt.request)
+			assert.Equal(t, tt.wantStatus, res.StatusCode)
+			assert.Equal(t, tt.wantBody, res.Body)
+			if tt.contentType != "" {
+				assert.Equal(t, tt.contentType, contentType(res.Headers))
+			}
+		})
+	}
+}
+
+func contentType(headers map[string]string) string {
+	for k, v := range headers {
+		if k == "Content-type" || k == "content-type" {
+			return v
+		}
+	}
+	return ""
+}
+
+func TestStoreConcurrency(t *testing.T) {
+	store := NewStore()
+	service := newStoreService(t, store)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			service.MatchRequest(context.Background(), Request{Method: "POST", Path: "/users", Body: fmt.Sprintf(`{"id": %d}`, i)})
+			service.MatchRequest(context.Background(), Request{Method: "GET", Path: "/users"})
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, []StoreSummary{{Name: "users", Resources: 50}}, store.Stores())
+}
+
+func TestStoreReset(t *testing.T) {
+	store := NewStore()
+	service := newStoreService(t, store)
+
+	service.MatchRequest(context.Background(), Request{Method: "POST", Path: "/users", Body: `{"id": "1"}`})
+	service.MatchRequest(context.Background(), Request{Method: "POST", Path: "/orders", Body: `{"number": 1}`})
+
+	resources, ok := store.Resources("users")
+	require.True(t, ok)
+	assert.Equal(t, map[string]any{"1": map[string]any{"id": "1"}}, resources)
+
+	store.Reset("users")
+	_, ok = store.Resources("users")
+	assert.False(t, ok)
+	assert.Equal(t, []StoreSummary{{Name: "orders", Resources: 1}}, store.Stores())
+
+	store.ResetAll()
+	assert.Empty(t, store.Stores())
+}
+
+func TestStoreMappingValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		store StoreMapping
+		want  ValidationErrors
+	}{
+		{
+			name:  "Should accept a list without key",
+			store: StoreMapping{Name: "users", Operation: StoreList},
+			want:  ValidationErrors{},
+		},
+		{
+			name:  "Should require the name and a known operation",
+			store: StoreMapping{Operation: "patch"},
+			want:  ValidationErrors{{"Store.Name", StoreNameMessage}, {"Store.Operation", StoreOperationMessage}},
+		},
+		{
+			name:  "Should require a key for single resources",
+			store: StoreMapping{Name: "users", Operation: StoreGet},
+			want:  ValidationErrors{{"Store.Key", StoreKeyMessage}},
+		},
+		{
+			name:  "Should require only one key",
+			store: StoreMapping{Name: "users", Operation: StorePut, Key: StoreKey{PathPattern: "^/users/(.+)$", JsonPath: "$.id"}},
+			want:  ValidationErrors{{"Store.Key", StoreKeyMessage}},
+		},
+		{
+			name:  "Should not accept a key for lists",
+			store: StoreMapping{Name: "users", Operation: StoreList, Key: StoreKey{JsonPath: "$.id"}},
+			want:  ValidationErrors{{"Store.Key", StoreListKeyMessage}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.store.Validate())
+		})
+	}
+}
+
+func TestStoreKeyCompile(t *testing.T) {
+	assert.EqualError(t, (&StoreKey{PathPattern: "^/users/.+$"}).compile(), "store key pattern must have a capture group: ^/users/.+$")
+	assert.ErrorContains(t, (&StoreKey{PathPattern: "^/users/(.+$"}).compile(), "failed to compile store key regex with pattern: ^/users/(.+$")
+	assert.ErrorContains(t, (&StoreKey{JsonPath: "$.["}).compile(), "failed to parse store key jsonpath expression: $.[")
+}
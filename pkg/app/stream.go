@@ -0,0 +1,95 @@
+package app
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	EventStreamContentType = "text/event-stream"
+
+	StreamNoEventsMessage = "Stream must have at least one event"
+	StreamLoopMessage     = "A looping stream must have at least one event with a delay"
+)
+
+// ServerSentEvent is a single event sent by a streaming response, after waiting for its delay.
+type ServerSentEvent struct {
+	ID    string   `json:"id,omitempty"`
+	Event string   `json:"event,omitempty"`
+	Data  string   `json:"data,omitempty"`
+	Retry int      `json:"retry,omitempty"`
+	Delay Duration `json:"delay,omitempty"`
+}
+
+// EventStream is a response sent as Server-Sent Events, when loop is true the events are sent
+// repeatedly until the client disconnects.
+type EventStream struct {
+	Loop   bool              `json:"loop,omitempty"`
+	Events []ServerSentEvent `json:"events"`
+}
+
+func (s *EventStream) Validate(field string) ValidationErrors {
+	errs := make(ValidationErrors, 0)
+	if len(s.Events) == 0 {
+		errs = append(errs, ValidationError{field + ".Events", StreamNoEventsMessage})
+	}
+
+	if s.Loop {
+		hasDelay := false
+		for _, e := range s.Events {
+			if e.Delay > 0 {
+				hasDelay = true
+				break
+			}
+		}
+		if !hasDelay {
+			errs = append(errs, ValidationError{field + ".Loop", StreamLoopMessage})
+		}
+	}
+
+	return errs
+}
+
+// Write sends the events to w, flushing after each one.
+// It stops at the first error, which happens when the client disconnects.
+func (s *EventStream) Write(w *bufio.Writer, sleep func(time.Duration)) error {
+	for {
+		for _, e := range s.Events {
+			if e.Delay > 0 {
+				sleep(time.Duration(e.Delay))
+			}
+
+			if _, err := w.WriteString(e.String()); err != nil {
+				return err
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+		}
+
+		if !s.Loop {
+			return nil
+		}
+	}
+}
+
+// String formats the event following the text/event-stream format.
+func (e ServerSentEvent) String() string {
+	var sb strings.Builder
+	if e.ID != "" {
+		fmt.Fprintf(&sb, "id: %s\n", e.ID)
+	}
+	if e.Event != "" {
+		fmt.Fprintf(&sb, "event: %s\n", e.Event)
+	}
+	if e.Retry > 0 {
+		fmt.Fprintf(&sb, "retry: %d\n", e.Retry)
+	}
+	for _, line := range strings.Split(e.Data, "\n") {
+		fmt.Fprintf(&sb, "data: %s\n", line)
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
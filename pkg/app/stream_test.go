@@ -0,0 +1,80 @@
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerSentEventString(t *testing.T) {
+	tests := []struct {
+		name  string
+		event ServerSentEvent
+		want  string
+	}{
+		{name: "Should format data only event", event: ServerSentEvent{Data: "hello"}, want: "data: hello\n\n"},
+		{name: "Should format complete event", event: ServerSentEvent{ID: "1", Event: "token", Retry: 500, Data: `{"text": "hi"}`}, want: "id: 1\nevent: token\nretry: 500\ndata: {\"text\": \"hi\"}\n\n"},
+		{name: "Should split multiline data", event: ServerSentEvent{Data: "line 1\nline 2"}, want: "data: line 1\ndata: line 2\n\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.event.String())
+		})
+	}
+}
+
+func TestEventStreamWrite(t *testing.T) {
+	stream := EventStream{Events: []ServerSentEvent{{Data: "first", Delay: Duration(time.Second)}, {Data: "second"}}}
+
+	var buf bytes.Buffer
+	slept := make([]time.Duration, 0)
+	err := stream.Write(bufio.NewWriter(&buf), func(d time.Duration) { slept = append(slept, d) })
+
+	require.NoError(t, err)
+	assert.Equal(t, "data: first\n\ndata: second\n\n", buf.String())
+	assert.Equal(t, []time.Duration{time.Second}, slept)
+}
+
+type disconnectingWriter struct {
+	writes int
+}
+
+func (w *disconnectingWriter) Write(p []byte) (int, error) {
+	if w.writes == 0 {
+		return 0, errors.New("connection closed")
+	}
+	w.writes--
+	return len(p), nil
+}
+
+func TestEventStreamStopsOnDisconnect(t *testing.T) {
+	stream := EventStream{Loop: true, Events: []ServerSentEvent{{Data: "ping", Delay: Duration(time.Millisecond)}}}
+	writer := &disconnectingWriter{writes: 5}
+
+	var sleeps int
+	err := stream.Write(bufio.NewWriter(writer), func(time.Duration) { sleeps++ })
+
+	assert.EqualError(t, err, "connection closed")
+	assert.Equal(t, 6, sleeps)
+}
+
+func TestValidateStream(t *testing.T) {
+	mapping := Mapping{
+		Request:           RequestMapping{Method: "GET", Path: CommonMatch{Exact: "/"}},
+		Response:          ResponseMapping{Stream: &EventStream{}},
+		Responses:         []ResponseMapping{{Stream: &EventStream{Loop: true, Events: []ServerSentEvent{{Data: "ping"}}}}},
+		ResponseSelection: ResponseSelection{Mode: SequenceSelection},
+	}
+
+	err := mapping.Validate()
+	assert.Equal(t, ValidationErrors{
+		{"Response.Stream.Events", StreamNoEventsMessage},
+		{"Responses[0].Stream.Loop", StreamLoopMessage},
+	}, err)
+}
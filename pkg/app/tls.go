@@ -0,0 +1,285 @@
+package app
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/americanas-go/config"
+	"github.com/americanas-go/log"
+	"github.com/pkg/errors"
+)
+
+const (
+	ClientAuthNone    = "none"
+	ClientAuthRequest = "request"
+	ClientAuthRequire = "require"
+
+	CACertFileName = "ca.pem"
+	CAKeyFileName  = "ca-key.pem"
+	CertFileName   = "cert.pem"
+	KeyFileName    = "key.pem"
+
+	certificateValidity = 365 * 24 * time.Hour
+)
+
+// TLSOptions configures the HTTPS listener.
+//
+// When no certificate and key files are given, a self-signed CA and a certificate issued by it
+// for the given hosts are generated, and written to ExportPath if set so clients can trust them.
+type TLSOptions struct {
+	CertFile     string
+	KeyFile      string
+	Hosts        []string
+	ExportPath   string
+	ClientAuth   string
+	ClientCAFile string
+}
+
+// NewTLSConfig builds the TLS configuration of the HTTPS listener, returning nil when it is disabled.
+func NewTLSConfig() (*tls.Config, error) {
+	if !config.Bool("server.tls.enabled") {
+		return nil, nil
+	}
+
+	return BuildTLSConfig(TLSOptions{
+		CertFile:     config.String("server.tls.certFile"),
+		KeyFile:      config.String("server.tls.keyFile"),
+		Hosts:        config.Strings("server.tls.hosts"),
+		ExportPath:   config.String("server.tls.exportPath"),
+		ClientAuth:   config.String("server.tls.clientAuth"),
+		ClientCAFile: config.String("server.tls.clientCAFile"),
+	})
+}
+
+func BuildTLSConfig(opts TLSOptions) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	var generatedCA *x509.Certificate
+
+	if opts.CertFile != "" || opts.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(opts.CertFile, opts.KeyFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "error loading TLS certificate")
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	} else {
+		bundle, err := GenerateCertificates(opts.Hosts)
+		if err != nil {
+			return nil, err
+		}
+
+		if opts.ExportPath != "" {
+			if err := bundle.Export(opts.ExportPath); err != nil {
+				return nil, err
+			}
+			log.Infof("generated TLS certificates exported to '%s'", opts.ExportPath)
+		}
+
+		cert, err := tls.X509KeyPair(bundle.Cert, bundle.Key)
+		if err != nil {
+			return nil, errors.Wrap(err, "error loading generated TLS certificate")
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+		generatedCA = bundle.CA
+	}
+
+	pool, err := clientCAPool(opts.ClientCAFile, generatedCA)
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(opts.ClientAuth) {
+	case "", ClientAuthNone:
+		tlsConfig.ClientAuth = tls.NoClientCert
+	case ClientAuthRequest:
+		tlsConfig.ClientAuth = tls.RequestClientCert
+		if pool != nil {
+			tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+		}
+	case ClientAuthRequire:
+		if pool == nil {
+			return nil, errors.New("a client CA file is required to verify client certificates")
+		}
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	default:
+		return nil, errors.Errorf("invalid client auth mode '%s', must be one of: none, request, require", opts.ClientAuth)
+	}
+	tlsConfig.ClientCAs = pool
+
+	return tlsConfig, nil
+}
+
+// clientCAPool returns the CAs used to verify client certificates, which are read from
+// the file if given, otherwise the generated CA is used.
+func clientCAPool(file string, generatedCA *x509.Certificate) (*x509.CertPool, error) {
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, errors.Wrap(err, "error reading client CA file")
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, errors.Errorf("no certificates found in client CA file '%s'", file)
+		}
+		return pool, nil
+	}
+
+	if generatedCA != nil {
+		pool := x509.NewCertPool()
+		pool.AddCert(generatedCA)
+		return pool, nil
+	}
+
+	return nil, nil
+}
+
+// CertificateBundle holds a generated CA and a certificate issued by it, PEM encoded.
+type CertificateBundle struct {
+	CA       *x509.Certificate
+	CAKey    *ecdsa.PrivateKey
+	CACert   []byte
+	CAKeyPEM []byte
+	Cert     []byte
+	Key      []byte
+}
+
+// GenerateCertificates creates a self-signed CA and a server certificate for the hosts.
+func GenerateCertificates(hosts []string) (*CertificateBundle, error) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, errors.Wrap(err, "error generating CA key")
+	}
+
+	caTemplate := &x509.Certificate{
+		SerialNumber:          serialNumber(),
+		Subject:               pkix.Name{CommonName: "Mantis CA", Organization: []string{"Mantis"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(certificateValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating CA certificate")
+	}
+
+	ca, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		return nil, errors.Wrap(err, "error parsing CA certificate")
+	}
+
+	cert, key, err := IssueCertificate(ca, caKey, "Mantis", hosts, x509.ExtKeyUsageServerAuth)
+	if err != nil {
+		return nil, err
+	}
+
+	caKeyPEM, err := encodeKey(caKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CertificateBundle{
+		CA:       ca,
+		CAKey:    caKey,
+		CACert:   pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}),
+		CAKeyPEM: caKeyPEM,
+		Cert:     cert,
+		Key:      key,
+	}, nil
+}
+
+// IssueCertificate creates a PEM encoded certificate and key signed by the CA.
+// Hosts that are IP addresses are added as IP SANs, the others as DNS names.
+func IssueCertificate(ca *x509.Certificate, caKey crypto.Signer, commonName string, hosts []string, usage x509.ExtKeyUsage) ([]byte, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "error generating certificate key")
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serialNumber(),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(certificateValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else if h != "" {
+			template.DNSNames = append(template.DNSNames, h)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "error creating certificate")
+	}
+
+	keyPEM, err := encodeKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), keyPEM, nil
+}
+
+// Export writes the CA and the certificate files to the folder, creating it if needed.
+func (b *CertificateBundle) Export(path string) error {
+	if err := os.MkdirAll(path, 0o755); err != nil {
+		return errors.Wrap(err, "error creating certificate export folder")
+	}
+
+	files := map[string][]byte{
+		CACertFileName: b.CACert,
+		CAKeyFileName:  b.CAKeyPEM,
+		CertFileName:   b.Cert,
+		KeyFileName:    b.Key,
+	}
+
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(path, name), data, 0o600); err != nil {
+			return errors.Wrapf(err, "error exporting certificate file '%s'", name)
+		}
+	}
+
+	return nil
+}
+
+func encodeKey(key *ecdsa.PrivateKey) ([]byte, error) {
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "error encoding private key")
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), nil
+}
+
+func serialNumber() *big.Int {
+	n, _ := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 127))
+	return n
+}
+
+// clientCertificateName returns the common name of the certificate presented by the client, if any.
+func clientCertificateName(state *tls.ConnectionState) string {
+	if state == nil || len(state.PeerCertificates) == 0 {
+		return ""
+	}
+	return state.PeerCertificates[0].Subject.CommonName
+}
@@ -0,0 +1,151 @@
+package app
+
+import (
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildTLSConfig(t *testing.T) {
+	bundle, err := GenerateCertificates([]string{"localhost"})
+	require.NoError(t, err)
+	dir := t.TempDir()
+	require.NoError(t, bundle.Export(dir))
+
+	tests := []struct {
+		name       string
+		opts       TLSOptions
+		clientAuth tls.ClientAuthType
+		wantErr    bool
+	}{
+		{
+			name:       "Should generate certificate without client auth",
+			opts:       TLSOptions{Hosts: []string{"localhost"}},
+			clientAuth: tls.NoClientCert,
+		},
+		{
+			name:       "Should require client certificates verified by the generated CA",
+			opts:       TLSOptions{Hosts: []string{"localhost"}, ClientAuth: ClientAuthRequire},
+			clientAuth: tls.RequireAndVerifyClientCert,
+		},
+		{
+			name:       "Should load provided certificate and request client certificates",
+			opts:       TLSOptions{CertFile: filepath.Join(dir, CertFileName), KeyFile: filepath.Join(dir, KeyFileName), ClientAuth: ClientAuthRequest},
+			clientAuth: tls.RequestClientCert,
+		},
+		{
+			name:       "Should verify client certificates with provided CA",
+			opts:       TLSOptions{CertFile: filepath.Join(dir, CertFileName), KeyFile: filepath.Join(dir, KeyFileName), ClientAuth: ClientAuthRequire, ClientCAFile: filepath.Join(dir, CACertFileName)},
+			clientAuth: tls.RequireAndVerifyClientCert,
+		},
+		{
+			name:    "Should fail to require client certificates without a CA",
+			opts:    TLSOptions{CertFile: filepath.Join(dir, CertFileName), KeyFile: filepath.Join(dir, KeyFileName), ClientAuth: ClientAuthRequire},
+			wantErr: true,
+		},
+		{
+			name:    "Should fail with invalid client auth mode",
+			opts:    TLSOptions{ClientAuth: "always"},
+			wantErr: true,
+		},
+		{
+			name:    "Should fail with missing certificate file",
+			opts:    TLSOptions{CertFile: filepath.Join(dir, "missing.pem"), KeyFile: filepath.Join(dir, KeyFileName)},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := BuildTLSConfig(tt.opts)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, cfg.Certificates, 1)
+			assert.Equal(t, tt.clientAuth, cfg.ClientAuth)
+		})
+	}
+}
+
+func TestTLSListenerWithClientCertificate(t *testing.T) {
+	dir := t.TempDir()
+	cfg, err := BuildTLSConfig(TLSOptions{Hosts: []string{"127.0.0.1"}, ExportPath: dir, ClientAuth: ClientAuthRequire})
+	require.NoError(t, err)
+
+	for _, name := range []string{CACertFileName, CAKeyFileName, CertFileName, KeyFileName} {
+		assert.FileExists(t, filepath.Join(dir, name))
+	}
+
+	var received Request
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.All("/*", NewHandler(mockService{func(r Request) MatchResult {
+		received = r
+		return MatchResult{StatusCode: http.StatusOK, Body: "ok"}
+	}}, nil, NewNoopTracing(), NewAccessLog()).All)
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", cfg)
+	require.NoError(t, err)
+	go func() { _ = app.Listener(ln) }()
+	defer func() { _ = app.Shutdown() }()
+
+	caPEM, err := os.ReadFile(filepath.Join(dir, CACertFileName))
+	require.NoError(t, err)
+	roots := x509.NewCertPool()
+	require.True(t, roots.AppendCertsFromPEM(caPEM))
+
+	bundle, err := GenerateCertificates(nil)
+	require.NoError(t, err)
+	url := "https://" + ln.Addr().String() + "/orders"
+
+	t.Run("Should reject client without a trusted certificate", func(t *testing.T) {
+		client := tlsClient(t, roots, bundle.Cert, bundle.Key)
+		_, err := client.Get(url)
+		assert.Error(t, err)
+	})
+
+	t.Run("Should accept client certificate issued by the CA", func(t *testing.T) {
+		ca, caKey := loadCA(t, dir)
+		cert, key, err := IssueCertificate(ca, caKey, "client-a", nil, x509.ExtKeyUsageClientAuth)
+		require.NoError(t, err)
+
+		res, err := tlsClient(t, roots, cert, key).Get(url)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		body, _ := io.ReadAll(res.Body)
+
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "ok", string(body))
+		assert.Equal(t, "https", received.Scheme)
+		assert.Equal(t, "client-a", received.ClientCertificate)
+	})
+}
+
+func tlsClient(t *testing.T, roots *x509.CertPool, certPEM, keyPEM []byte) *http.Client {
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	require.NoError(t, err)
+
+	return &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: []tls.Certificate{cert}},
+		},
+	}
+}
+
+func loadCA(t *testing.T, dir string) (*x509.Certificate, crypto.Signer) {
+	pair, err := tls.LoadX509KeyPair(filepath.Join(dir, CACertFileName), filepath.Join(dir, CAKeyFileName))
+	require.NoError(t, err)
+	ca, err := x509.ParseCertificate(pair.Certificate[0])
+	require.NoError(t, err)
+	return ca, pair.PrivateKey.(crypto.Signer)
+}
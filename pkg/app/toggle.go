@@ -0,0 +1,76 @@
+package app
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// MappingToggles keeps the mappings disabled at runtime, either by ID or by tag.
+//
+// The state is replaced as a whole on every change, so it can be read while matching without locking.
+type MappingToggles struct {
+	mu    sync.Mutex
+	state atomic.Pointer[toggleState]
+}
+
+type toggleState struct {
+	ids  map[string]bool
+	tags map[string]bool
+}
+
+func NewMappingToggles() *MappingToggles {
+	t := &MappingToggles{}
+	t.state.Store(&toggleState{ids: map[string]bool{}, tags: map[string]bool{}})
+	return t
+}
+
+// Enabled reports whether the mapping is enabled, a mapping is disabled if its ID or any of its tags is.
+func (t *MappingToggles) Enabled(m *Mapping) bool {
+	state := t.state.Load()
+	if len(state.ids) == 0 && len(state.tags) == 0 {
+		return true
+	}
+
+	if state.ids[m.ID] {
+		return false
+	}
+	for _, tag := range m.Tags {
+		if state.tags[tag] {
+			return false
+		}
+	}
+	return true
+}
+
+func (t *MappingToggles) SetMapping(id string, enabled bool) {
+	t.update(func(s *toggleState) { toggle(s.ids, id, enabled) })
+}
+
+func (t *MappingToggles) SetTag(tag string, enabled bool) {
+	t.update(func(s *toggleState) { toggle(s.tags, tag, enabled) })
+}
+
+func (t *MappingToggles) update(change func(*toggleState)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	current := t.state.Load()
+	next := &toggleState{ids: make(map[string]bool, len(current.ids)), tags: make(map[string]bool, len(current.tags))}
+	for k := range current.ids {
+		next.ids[k] = true
+	}
+	for k := range current.tags {
+		next.tags[k] = true
+	}
+
+	change(next)
+	t.state.Store(next)
+}
+
+func toggle(disabled map[string]bool, key string, enabled bool) {
+	if enabled {
+		delete(disabled, key)
+		return
+	}
+	disabled[key] = true
+}
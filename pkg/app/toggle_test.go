@@ -0,0 +1,28 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMappingToggles(t *testing.T) {
+	users := &Mapping{ID: "users", Tags: []string{"users"}}
+	orders := &Mapping{ID: "orders", Tags: []string{"orders", "slow"}}
+
+	toggles := NewMappingToggles()
+	assert.True(t, toggles.Enabled(users))
+	assert.True(t, toggles.Enabled(orders))
+
+	toggles.SetMapping("users", false)
+	assert.False(t, toggles.Enabled(users))
+	assert.True(t, toggles.Enabled(orders))
+
+	toggles.SetTag("slow", false)
+	assert.False(t, toggles.Enabled(orders))
+
+	toggles.SetMapping("users", true)
+	toggles.SetTag("slow", true)
+	assert.True(t, toggles.Enabled(users))
+	assert.True(t, toggles.Enabled(orders))
+}
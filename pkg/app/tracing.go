@@ -0,0 +1,105 @@
+package app
+
+import (
+	"context"
+
+	"github.com/americanas-go/config"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+const (
+	DefaultTracingServiceName = "mantis"
+
+	tracerName = "github.com/dubonzi/mantis"
+
+	MatchedAttribute     = attribute.Key("mantis.matched")
+	MappingFileAttribute = attribute.Key("mantis.mapping_file")
+	ScenarioAttribute    = attribute.Key("mantis.scenario")
+)
+
+// Tracing creates the spans of the request pipeline, it does nothing unless tracing is enabled.
+type Tracing struct {
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+	provider   *sdktrace.TracerProvider
+}
+
+func NewTracing() (*Tracing, error) {
+	if !config.Bool("tracing.enabled") {
+		return NewNoopTracing(), nil
+	}
+
+	opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(config.String("tracing.endpoint"))}
+	if config.Bool("tracing.insecure") {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+
+	exporter, err := otlptracehttp.New(context.Background(), opts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating trace exporter")
+	}
+
+	serviceName := config.String("tracing.serviceName")
+	if serviceName == "" {
+		serviceName = DefaultTracingServiceName
+	}
+
+	return NewTracingWithExporter(exporter, serviceName), nil
+}
+
+// NewTracingWithExporter creates a Tracing that sends its spans, in batches, to the exporter.
+func NewTracingWithExporter(exporter sdktrace.SpanExporter, serviceName string) *Tracing {
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(serviceName))),
+	)
+
+	return &Tracing{
+		tracer:     provider.Tracer(tracerName),
+		propagator: propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}),
+		provider:   provider,
+	}
+}
+
+func NewNoopTracing() *Tracing {
+	return &Tracing{
+		tracer:     noop.NewTracerProvider().Tracer(tracerName),
+		propagator: propagation.NewCompositeTextMapPropagator(),
+	}
+}
+
+// Extract returns a context carrying the trace context found in the request headers, if any.
+func (t *Tracing) Extract(ctx context.Context, r Request) context.Context {
+	return t.propagator.Extract(ctx, propagation.MapCarrier(r.Headers))
+}
+
+func (t *Tracing) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return t.tracer.Start(ctx, name, opts...)
+}
+
+// Shutdown flushes the spans not yet exported.
+func (t *Tracing) Shutdown(ctx context.Context) error {
+	if t.provider == nil {
+		return nil
+	}
+	return t.provider.Shutdown(ctx)
+}
+
+func matchAttributes(mapping Mapping, matched bool) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{MatchedAttribute.Bool(matched)}
+	if mapping.FilePath != "" {
+		attrs = append(attrs, MappingFileAttribute.String(mapping.FilePath))
+	}
+	if mapping.Scenario != nil {
+		attrs = append(attrs, ScenarioAttribute.String(mapping.Scenario.Name))
+	}
+	return attrs
+}
@@ -0,0 +1,51 @@
+package app
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracing(t *testing.T) {
+	mappings := getMappings()
+	matcher := newTestMatcher(mappings)
+	exporter := tracetest.NewInMemoryExporter()
+	tracing := NewTracingWithExporter(exporter, "mantis-test")
+
+	service := NewService(mappings, matcher, NewScenarioHandler(matcher), &mockDelayer{}, NewResponseSelector(), NewCallbackDispatcher(&mockDelayer{}), NewStore(), NewJournal(), NewMetrics(mappings, nil), tracing)
+	app := fiber.New()
+	app.All("/*", NewHandler(service, nil, tracing, NewAccessLog()).All)
+
+	req := httptest.NewRequest("GET", "/simple", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	_, err := app.Test(req)
+	require.NoError(t, err)
+	require.NoError(t, tracing.provider.ForceFlush(context.Background()))
+
+	spans := exporter.GetSpans().Snapshots()
+	byName := make(map[string]map[attribute.Key]attribute.Value)
+	for _, s := range spans {
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", s.SpanContext().TraceID().String())
+
+		attrs := make(map[attribute.Key]attribute.Value)
+		for _, a := range s.Attributes() {
+			attrs[a.Key] = a.Value
+		}
+		byName[s.Name()] = attrs
+	}
+
+	require.Len(t, byName, 5)
+	assert.Contains(t, byName, "mantis.response.write")
+	assert.Contains(t, byName, "mantis.delay")
+	assert.False(t, byName["mantis.match.scenario"][MatchedAttribute].AsBool())
+	assert.True(t, byName["mantis.match"][MatchedAttribute].AsBool())
+	assert.Equal(t, "file_3", byName["mantis.match"][MappingFileAttribute].AsString())
+	assert.True(t, byName["mantis.request"][MatchedAttribute].AsBool())
+	assert.Equal(t, "file_3", byName["mantis.request"][MappingFileAttribute].AsString())
+}
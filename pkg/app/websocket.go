@@ -0,0 +1,213 @@
+package app
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/americanas-go/log"
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	IncomingMessage = "in"
+	OutgoingMessage = "out"
+
+	WebSocketRuleRepliesMessage = "Message rule must have at least one reply"
+	WebSocketScheduledMessage   = "Scheduled message data is required"
+)
+
+// WebSocketMapping defines how Mantis behaves after upgrading a matched request to a WebSocket connection.
+//
+// Incoming frames are checked against each message rule in order, the first rule that matches
+// sends its replies. Scheduled messages are pushed by the server after their delay, repeating
+// on every interval if one is defined.
+type WebSocketMapping struct {
+	Messages  []WebSocketMessageRule `json:"messages,omitempty"`
+	Scheduled []WebSocketFrame       `json:"scheduled,omitempty"`
+}
+
+type WebSocketMessageRule struct {
+	Match   BodyMatch        `json:"match"`
+	Replies []WebSocketFrame `json:"replies"`
+}
+
+type WebSocketFrame struct {
+	Data     string   `json:"data"`
+	Delay    Duration `json:"delay,omitempty"`
+	Interval Duration `json:"interval,omitempty"`
+}
+
+func (w *WebSocketMapping) Validate() ValidationErrors {
+	errs := make(ValidationErrors, 0)
+	for i, m := range w.Messages {
+		if len(m.Replies) == 0 {
+			errs = append(errs, ValidationError{fmt.Sprintf("WebSocket.Messages[%d].Replies", i), WebSocketRuleRepliesMessage})
+		}
+	}
+	for i, s := range w.Scheduled {
+		if s.Data == "" {
+			errs = append(errs, ValidationError{fmt.Sprintf("WebSocket.Scheduled[%d].Data", i), WebSocketScheduledMessage})
+		}
+	}
+	return errs
+}
+
+// WebSocketMessage is a frame sent or received during a WebSocket session.
+type WebSocketMessage struct {
+	Direction string `json:"direction"`
+	Data      string `json:"data"`
+	Matched   bool   `json:"matched,omitempty"`
+	Date      string `json:"date"`
+}
+
+type WebSocketHandler struct {
+	matcher *Matcher
+	journal *Journal
+}
+
+func NewWebSocketHandler(matcher *Matcher, journal *Journal) *WebSocketHandler {
+	return &WebSocketHandler{matcher, journal}
+}
+
+// Upgrade upgrades the request to a WebSocket connection and runs the session defined by the mapping.
+func (h *WebSocketHandler) Upgrade(c *fiber.Ctx, mapping *WebSocketMapping, r Request) error {
+	if !websocket.IsWebSocketUpgrade(c) {
+		return fiber.ErrUpgradeRequired
+	}
+
+	return websocket.New(func(conn *websocket.Conn) {
+		h.session(conn, mapping, r)
+	})(c)
+}
+
+type webSocketSession struct {
+	conn      *websocket.Conn
+	requestID string
+	journal   *Journal
+	writeMu   sync.Mutex
+	done      chan struct{}
+}
+
+func (h *WebSocketHandler) session(conn *websocket.Conn, mapping *WebSocketMapping, r Request) {
+	s := &webSocketSession{
+		conn:      conn,
+		requestID: r.ID,
+		journal:   h.journal,
+		done:      make(chan struct{}),
+	}
+	defer s.close()
+
+	for _, frame := range mapping.Scheduled {
+		go s.schedule(frame)
+	}
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				log.Debugf("websocket session closed: %s", err)
+			}
+			return
+		}
+
+		rule := h.findRule(mapping, string(data))
+		s.record(IncomingMessage, string(data), rule != nil)
+
+		if rule != nil {
+			go s.reply(rule.Replies)
+		}
+	}
+}
+
+// close ends the session, waiting for any write in progress so the connection is not used afterwards.
+func (s *webSocketSession) close() {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	close(s.done)
+}
+
+func (h *WebSocketHandler) findRule(mapping *WebSocketMapping, data string) *WebSocketMessageRule {
+	for i := range mapping.Messages {
+		if h.matcher.MatchBody(mapping.Messages[i].Match, data) {
+			return &mapping.Messages[i]
+		}
+	}
+	return nil
+}
+
+func (s *webSocketSession) reply(frames []WebSocketFrame) {
+	for _, f := range frames {
+		if !s.wait(time.Duration(f.Delay)) {
+			return
+		}
+		if err := s.write(f.Data); err != nil {
+			return
+		}
+	}
+}
+
+func (s *webSocketSession) schedule(frame WebSocketFrame) {
+	if !s.wait(time.Duration(frame.Delay)) {
+		return
+	}
+
+	for {
+		if err := s.write(frame.Data); err != nil {
+			return
+		}
+		if frame.Interval <= 0 || !s.wait(time.Duration(frame.Interval)) {
+			return
+		}
+	}
+}
+
+// wait sleeps for the duration, returning false if the session ended meanwhile.
+func (s *webSocketSession) wait(d time.Duration) bool {
+	if d <= 0 {
+		select {
+		case <-s.done:
+			return false
+		default:
+			return true
+		}
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-s.done:
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+func (s *webSocketSession) write(data string) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	select {
+	case <-s.done:
+		return websocket.ErrCloseSent
+	default:
+	}
+
+	if err := s.conn.WriteMessage(websocket.TextMessage, []byte(data)); err != nil {
+		return err
+	}
+	s.record(OutgoingMessage, data, false)
+	return nil
+}
+
+func (s *webSocketSession) record(direction, data string, matched bool) {
+	s.journal.RecordMessage(s.requestID, WebSocketMessage{
+		Direction: direction,
+		Data:      data,
+		Matched:   matched,
+		Date:      time.Now().Format(time.RFC3339Nano),
+	})
+}
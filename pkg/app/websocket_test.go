@@ -0,0 +1,133 @@
+package app
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	fasthttpws "github.com/fasthttp/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestWebSocketServer(t *testing.T, mappings Mappings) (string, *Journal) {
+	t.Helper()
+
+	matcher := newTestMatcher(mappings)
+	for _, method := range mappings {
+		for _, m := range method {
+			for _, rule := range m.WebSocket.Messages {
+				_ = matcher.jsonPathCache.AddExpressions(rule.Match.JsonPath)
+			}
+		}
+	}
+
+	journal := NewJournal()
+	service := NewService(mappings, matcher, NewScenarioHandler(matcher), &mockDelayer{}, NewResponseSelector(), NewCallbackDispatcher(&mockDelayer{}), NewStore(), journal, NewMetrics(mappings, nil), NewNoopTracing())
+	handler := NewHandler(service, NewWebSocketHandler(matcher, journal), NewNoopTracing(), NewAccessLog())
+
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.All("/*", handler.All)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() { _ = app.Listener(ln) }()
+	t.Cleanup(func() { _ = app.Shutdown() })
+
+	return ln.Addr().String(), journal
+}
+
+func TestWebSocketSession(t *testing.T) {
+	mappings := make(Mappings)
+	_ = mappings.Put(Mapping{
+		Request: RequestMapping{Method: "GET", Path: CommonMatch{Exact: "/ws"}},
+		WebSocket: &WebSocketMapping{
+			Messages: []WebSocketMessageRule{
+				{Match: BodyMatch{JsonPath: []string{"$.subscribe"}}, Replies: []WebSocketFrame{{Data: `{"type": "subscribed"}`}, {Data: `{"price": 10}`, Delay: Duration(time.Millisecond)}}},
+				{Match: BodyMatch{CommonMatch: CommonMatch{Contains: []string{"ping"}}}, Replies: []WebSocketFrame{{Data: "pong"}}},
+			},
+			Scheduled: []WebSocketFrame{{Data: "welcome"}},
+		},
+		MaxScore: 1,
+		FilePath: "file_ws",
+	})
+
+	addr, journal := newTestWebSocketServer(t, mappings)
+
+	conn, _, err := fasthttpws.DefaultDialer.Dial("ws://"+addr+"/ws", nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	read := func() string {
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(2*time.Second)))
+		_, data, err := conn.ReadMessage()
+		require.NoError(t, err)
+		return string(data)
+	}
+
+	assert.Equal(t, "welcome", read())
+
+	require.NoError(t, conn.WriteMessage(fasthttpws.TextMessage, []byte(`{"subscribe": "prices"}`)))
+	assert.Equal(t, `{"type": "subscribed"}`, read())
+	assert.Equal(t, `{"price": 10}`, read())
+
+	require.NoError(t, conn.WriteMessage(fasthttpws.TextMessage, []byte("unknown")))
+	require.NoError(t, conn.WriteMessage(fasthttpws.TextMessage, []byte("ping")))
+	assert.Equal(t, "pong", read())
+
+	require.Eventually(t, func() bool {
+		entries := journal.Entries()
+		return len(entries) == 1 && len(entries[0].Messages) == 7
+	}, time.Second, 10*time.Millisecond)
+
+	entries := journal.Entries()
+	assert.True(t, entries[0].Matched)
+	assert.Equal(t, "file_ws", entries[0].MappingFile)
+
+	messages := make([]WebSocketMessage, 0)
+	for _, m := range entries[0].Messages {
+		m.Date = ""
+		messages = append(messages, m)
+	}
+	assert.Equal(t, []WebSocketMessage{
+		{Direction: OutgoingMessage, Data: "welcome"},
+		{Direction: IncomingMessage, Data: `{"subscribe": "prices"}`, Matched: true},
+		{Direction: OutgoingMessage, Data: `{"type": "subscribed"}`},
+		{Direction: OutgoingMessage, Data: `{"price": 10}`},
+		{Direction: IncomingMessage, Data: "unknown"},
+		{Direction: IncomingMessage, Data: "ping", Matched: true},
+		{Direction: OutgoingMessage, Data: "pong"},
+	}, messages)
+}
+
+func TestWebSocketRequiresUpgrade(t *testing.T) {
+	mappings := make(Mappings)
+	_ = mappings.Put(Mapping{
+		Request:   RequestMapping{Method: "GET", Path: CommonMatch{Exact: "/ws"}},
+		WebSocket: &WebSocketMapping{},
+		MaxScore:  1,
+	})
+
+	addr, _ := newTestWebSocketServer(t, mappings)
+
+	agent := fiber.Get("http://" + addr + "/ws")
+	code, _, errs := agent.Bytes()
+	require.Empty(t, errs)
+	assert.Equal(t, fiber.StatusUpgradeRequired, code)
+}
+
+func TestValidateWebSocket(t *testing.T) {
+	mapping := Mapping{
+		Request: RequestMapping{Method: "GET", Path: CommonMatch{Exact: "/ws"}},
+		WebSocket: &WebSocketMapping{
+			Messages:  []WebSocketMessageRule{{Match: BodyMatch{CommonMatch: CommonMatch{Exact: "ping"}}}},
+			Scheduled: []WebSocketFrame{{Delay: Duration(time.Second)}},
+		},
+	}
+
+	assert.Equal(t, ValidationErrors{
+		{"WebSocket.Messages[0].Replies", WebSocketRuleRepliesMessage},
+		{"WebSocket.Scheduled[0].Data", WebSocketScheduledMessage},
+	}, mapping.Validate())
+}
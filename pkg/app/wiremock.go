@@ -0,0 +1,452 @@
+package app
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// WireMockStartedState is the state every WireMock scenario starts in.
+	WireMockStartedState = "Started"
+)
+
+var (
+	wireMockStubFields     = []string{"id", "uuid", "name", "request", "response", "scenarioName", "requiredScenarioState", "newScenarioState", "metadata"}
+	wireMockRequestFields  = []string{"method", "url", "urlPath", "urlPattern", "urlPathPattern", "queryParameters", "headers", "host", "port", "scheme", "bodyPatterns"}
+	wireMockResponseFields = []string{"status", "headers", "body", "jsonBody", "base64Body", "bodyFileName", "fixedDelayMilliseconds"}
+)
+
+type wireMockFile struct {
+	Mappings []json.RawMessage `json:"mappings"`
+}
+
+type wireMockStub struct {
+	ID                    string           `json:"id"`
+	UUID                  string           `json:"uuid"`
+	Name                  string           `json:"name"`
+	Request               wireMockRequest  `json:"request"`
+	Response              wireMockResponse `json:"response"`
+	ScenarioName          string           `json:"scenarioName"`
+	RequiredScenarioState string           `json:"requiredScenarioState"`
+	NewScenarioState      string           `json:"newScenarioState"`
+}
+
+type wireMockRequest struct {
+	Method          string                     `json:"method"`
+	URL             string                     `json:"url"`
+	URLPath         string                     `json:"urlPath"`
+	URLPattern      string                     `json:"urlPattern"`
+	URLPathPattern  string                     `json:"urlPathPattern"`
+	QueryParameters map[string]wireMockPattern `json:"queryParameters"`
+	Headers         map[string]wireMockPattern `json:"headers"`
+	Host            *wireMockPattern           `json:"host"`
+	Port            int                        `json:"port"`
+	Scheme          string                     `json:"scheme"`
+	BodyPatterns    []wireMockPattern          `json:"bodyPatterns"`
+}
+
+type wireMockResponse struct {
+	Status                 int             `json:"status"`
+	Headers                map[string]any  `json:"headers"`
+	Body                   string          `json:"body"`
+	JSONBody               json.RawMessage `json:"jsonBody"`
+	Base64Body             string          `json:"base64Body"`
+	BodyFileName           string          `json:"bodyFileName"`
+	FixedDelayMilliseconds int             `json:"fixedDelayMilliseconds"`
+}
+
+// wireMockPattern is a WireMock value matcher, only one of the operators is expected to be set.
+type wireMockPattern map[string]json.RawMessage
+
+// IsWireMockStub reports whether the content is a WireMock stub, or a file with a list of stubs.
+func IsWireMockStub(content []byte) bool {
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(content, &doc); err != nil {
+		return false
+	}
+
+	if _, ok := doc["mappings"]; ok {
+		return true
+	}
+
+	var stub struct {
+		Request  map[string]json.RawMessage `json:"request"`
+		Response map[string]json.RawMessage `json:"response"`
+	}
+	if err := json.Unmarshal(content, &stub); err != nil {
+		return false
+	}
+
+	for _, field := range []string{"url", "urlPath", "urlPattern", "urlPathPattern", "bodyPatterns", "queryParameters"} {
+		if _, ok := stub.Request[field]; ok {
+			return true
+		}
+	}
+	for _, field := range []string{"status", "jsonBody", "bodyFileName", "fixedDelayMilliseconds"} {
+		if _, ok := stub.Response[field]; ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ConvertWireMock converts a WireMock stub, or a file with a list of stubs, to mappings.
+//
+// Features that can't be converted are left out of the mapping and described in the returned warnings,
+// stubs that can't be matched without them are skipped.
+func ConvertWireMock(content []byte) ([]Mapping, []string, error) {
+	var file wireMockFile
+	if err := json.Unmarshal(content, &file); err != nil {
+		return nil, nil, err
+	}
+
+	stubs := file.Mappings
+	if stubs == nil {
+		stubs = []json.RawMessage{content}
+	}
+
+	mappings := make([]Mapping, 0, len(stubs))
+	var warnings []string
+	for i, raw := range stubs {
+		c := wireMockConverter{}
+		m, ok, err := c.convert(raw)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "error converting WireMock stub %d", i)
+		}
+
+		label := fmt.Sprintf("stub %d", i)
+		if m.ID != "" {
+			label = fmt.Sprintf("stub '%s'", m.ID)
+		}
+		for _, w := range c.warnings {
+			warnings = append(warnings, fmt.Sprintf("%s: %s", label, w))
+		}
+
+		if ok {
+			mappings = append(mappings, m)
+		}
+	}
+
+	return mappings, warnings, nil
+}
+
+type wireMockConverter struct {
+	warnings []string
+}
+
+func (c *wireMockConverter) unsupported(format string, args ...any) {
+	c.warnings = append(c.warnings, fmt.Sprintf(format, args...))
+}
+
+// convert converts a single stub, returning false if it can't be matched.
+func (c *wireMockConverter) convert(raw json.RawMessage) (Mapping, bool, error) {
+	var stub wireMockStub
+	if err := json.Unmarshal(raw, &stub); err != nil {
+		return Mapping{}, false, err
+	}
+
+	if err := c.unknownFields(raw, "", wireMockStubFields); err != nil {
+		return Mapping{}, false, err
+	}
+
+	m := Mapping{ID: stub.ID, Name: stub.Name}
+	if m.ID == "" {
+		m.ID = stub.UUID
+	}
+
+	if stub.ScenarioName != "" {
+		state := stub.RequiredScenarioState
+		if state == "" {
+			c.unsupported("scenario stubs without 'requiredScenarioState' only match in the '%s' state", WireMockStartedState)
+			state = WireMockStartedState
+		}
+		m.Scenario = &ScenarioMapping{
+			Name:          stub.ScenarioName,
+			StartingState: state == WireMockStartedState,
+			State:         state,
+			NewState:      stub.NewScenarioState,
+		}
+	}
+
+	ok, err := c.request(raw, stub.Request, &m.Request)
+	if err != nil {
+		return m, false, err
+	}
+
+	if err := c.response(raw, stub.Response, &m.Response); err != nil {
+		return m, false, err
+	}
+
+	return m, ok, nil
+}
+
+func (c *wireMockConverter) request(raw json.RawMessage, req wireMockRequest, m *RequestMapping) (bool, error) {
+	var stub struct {
+		Request json.RawMessage `json:"request"`
+	}
+	if err := json.Unmarshal(raw, &stub); err != nil {
+		return false, err
+	}
+	if err := c.unknownFields(stub.Request, "request.", wireMockRequestFields); err != nil {
+		return false, err
+	}
+
+	m.Method = strings.ToUpper(req.Method)
+	if m.Method == "" || m.Method == "ANY" {
+		c.unsupported("requests with any method are not supported, the stub is skipped")
+		return false, nil
+	}
+
+	var queryPatterns []string
+	for _, name := range sortedKeys(req.QueryParameters) {
+		pattern, ok := c.queryPattern(name, req.QueryParameters[name])
+		if ok {
+			queryPatterns = append(queryPatterns, pattern)
+		}
+	}
+
+	switch {
+	case req.URL != "":
+		m.Path = CommonMatch{Exact: req.URL}
+	case req.URLPath != "":
+		m.Path = CommonMatch{Patterns: []string{"^" + regexp.QuoteMeta(req.URLPath) + `(\?.*)?$`}}
+	case req.URLPattern != "":
+		m.Path = CommonMatch{Patterns: []string{"^(?:" + req.URLPattern + ")$"}}
+	case req.URLPathPattern != "":
+		m.Path = CommonMatch{Patterns: []string{"^(?:" + req.URLPathPattern + `)(\?.*)?$`}}
+	default:
+		m.Path = CommonMatch{Patterns: []string{".*"}}
+	}
+
+	if len(queryPatterns) > 0 {
+		if m.Path.Exact != "" {
+			c.unsupported("'request.queryParameters' are ignored when 'request.url' is used, as it already matches the query string")
+		} else {
+			m.Path.Patterns = append(m.Path.Patterns, queryPatterns...)
+		}
+	}
+
+	for _, name := range sortedKeys(req.Headers) {
+		match, ok := c.commonMatch("request.headers."+name, req.Headers[name])
+		if ok {
+			if m.Headers == nil {
+				m.Headers = map[string]CommonMatch{}
+			}
+			m.Headers[strings.ToLower(name)] = match
+		}
+	}
+
+	if req.Host != nil {
+		if match, ok := c.commonMatch("request.host", *req.Host); ok {
+			m.Host = match
+		}
+	}
+	if req.Port != 0 {
+		m.Port = fmt.Sprint(req.Port)
+	}
+	m.Scheme = req.Scheme
+
+	for i, p := range req.BodyPatterns {
+		c.bodyPattern(fmt.Sprintf("request.bodyPatterns[%d]", i), p, &m.Body)
+	}
+
+	return true, nil
+}
+
+func (c *wireMockConverter) queryPattern(name string, p wireMockPattern) (string, bool) {
+	field := "request.queryParameters." + name
+	op, value, ok := c.operator(field, p, "equalTo", "contains", "matches")
+	if !ok {
+		return "", false
+	}
+
+	prefix := `[?&]` + regexp.QuoteMeta(name) + `=`
+	switch op {
+	case "equalTo":
+		return prefix + regexp.QuoteMeta(value) + `(&|$)`, true
+	case "contains":
+		return prefix + `[^&]*` + regexp.QuoteMeta(value), true
+	default:
+		return prefix + `(?:` + value + `)(&|$)`, true
+	}
+}
+
+func (c *wireMockConverter) commonMatch(field string, p wireMockPattern) (CommonMatch, bool) {
+	op, value, ok := c.operator(field, p, "equalTo", "contains", "matches")
+	if !ok {
+		return CommonMatch{}, false
+	}
+
+	switch op {
+	case "equalTo":
+		return CommonMatch{Exact: value}, true
+	case "contains":
+		return CommonMatch{Contains: []string{value}}, true
+	default:
+		return CommonMatch{Patterns: []string{"^(?:" + value + ")$"}}, true
+	}
+}
+
+func (c *wireMockConverter) bodyPattern(field string, p wireMockPattern, body *BodyMatch) {
+	if raw, ok := p["equalToJson"]; ok && len(p) == 1 {
+		value := string(raw)
+		var s string
+		if json.Unmarshal(raw, &s) == nil {
+			value = s
+		}
+		c.unsupported("'%s.equalToJson' is matched as an exact string, whitespace and field order must match", field)
+		c.setExact(field, body, value)
+		return
+	}
+
+	op, value, ok := c.operator(field, p, "equalTo", "contains", "matches", "matchesJsonPath")
+	if !ok {
+		return
+	}
+
+	switch op {
+	case "equalTo":
+		c.setExact(field, body, value)
+	case "contains":
+		body.Contains = append(body.Contains, value)
+	case "matches":
+		body.Patterns = append(body.Patterns, "^(?:"+value+")$")
+	default:
+		body.JsonPath = append(body.JsonPath, value)
+	}
+}
+
+func (c *wireMockConverter) setExact(field string, body *BodyMatch, value string) {
+	if body.Exact != "" {
+		c.unsupported("'%s' is ignored, only one exact body match is supported", field)
+		return
+	}
+	body.Exact = value
+}
+
+// operator returns the operator of the pattern and its value, if it is one of the supported ones
+// and has a string value without any extra options.
+func (c *wireMockConverter) operator(field string, p wireMockPattern, supported ...string) (string, string, bool) {
+	for _, op := range supported {
+		raw, ok := p[op]
+		if !ok {
+			continue
+		}
+
+		var value string
+		if err := json.Unmarshal(raw, &value); err != nil {
+			c.unsupported("'%s.%s' with a non string value is not supported", field, op)
+			return "", "", false
+		}
+
+		if len(p) > 1 {
+			c.unsupported("options of '%s.%s' are not supported: %s", field, op, strings.Join(otherKeys(p, op), ", "))
+		}
+
+		return op, value, true
+	}
+
+	c.unsupported("'%s' matcher is not supported: %s", field, strings.Join(sortedKeys(p), ", "))
+	return "", "", false
+}
+
+func (c *wireMockConverter) response(raw json.RawMessage, res wireMockResponse, m *ResponseMapping) error {
+	var stub struct {
+		Response json.RawMessage `json:"response"`
+	}
+	if err := json.Unmarshal(raw, &stub); err != nil {
+		return err
+	}
+	if err := c.unknownFields(stub.Response, "response.", wireMockResponseFields); err != nil {
+		return err
+	}
+
+	m.StatusCode = res.Status
+	if m.StatusCode == 0 {
+		m.StatusCode = 200
+	}
+
+	for _, name := range sortedKeys(res.Headers) {
+		if m.Headers == nil {
+			m.Headers = map[string]string{}
+		}
+		switch v := res.Headers[name].(type) {
+		case []any:
+			values := make([]string, 0, len(v))
+			for _, e := range v {
+				values = append(values, fmt.Sprint(e))
+			}
+			m.Headers[name] = strings.Join(values, ", ")
+		default:
+			m.Headers[name] = fmt.Sprint(v)
+		}
+	}
+
+	switch {
+	case res.Body != "":
+		m.Body = res.Body
+	case len(res.JSONBody) > 0:
+		m.Body = string(res.JSONBody)
+	case res.Base64Body != "":
+		body, err := base64.StdEncoding.DecodeString(res.Base64Body)
+		if err != nil {
+			return errors.Wrap(err, "error decoding 'response.base64Body'")
+		}
+		m.Body = string(body)
+	case res.BodyFileName != "":
+		m.BodyFile = res.BodyFileName
+	}
+
+	if res.FixedDelayMilliseconds > 0 {
+		m.ResponseDelay = Delay{Fixed: FixedDelay{Duration: Duration(time.Duration(res.FixedDelayMilliseconds) * time.Millisecond)}}
+	}
+
+	return nil
+}
+
+// unknownFields reports the fields of the object that are not converted.
+func (c *wireMockConverter) unknownFields(raw json.RawMessage, prefix string, known []string) error {
+	if len(raw) == 0 {
+		return nil
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return err
+	}
+
+	for _, name := range sortedKeys(fields) {
+		if !slices.Contains(known, name) {
+			c.unsupported("'%s%s' is not supported", prefix, name)
+		}
+	}
+	return nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func otherKeys(p wireMockPattern, key string) []string {
+	keys := make([]string, 0, len(p))
+	for _, k := range sortedKeys(p) {
+		if k != key {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
@@ -0,0 +1,168 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsWireMockStub(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    bool
+	}{
+		{name: "Should detect a stub", content: `{"request": {"method": "GET", "url": "/a"}, "response": {"status": 200}}`, want: true},
+		{name: "Should detect a list of stubs", content: `{"mappings": []}`, want: true},
+		{name: "Should not detect a mapping", content: `{"request": {"method": "GET", "path": {"exact": "/a"}}, "response": {"statusCode": 200}}`, want: false},
+		{name: "Should not detect a list of mappings", content: `[{"request": {"method": "GET"}}]`, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsWireMockStub([]byte(tt.content)))
+		})
+	}
+}
+
+func TestConvertWireMock(t *testing.T) {
+	tests := []struct {
+		name         string
+		content      string
+		wantMappings []Mapping
+		wantWarnings []string
+	}{
+		{
+			name: "Should convert url path, query, header and body matchers",
+			content: `{
+				"id": "create-order",
+				"request": {
+					"method": "post",
+					"urlPath": "/orders",
+					"queryParameters": {"tenant": {"matches": "[a-z]+"}, "dryRun": {"contains": "tr"}},
+					"headers": {"Content-Type": {"equalTo": "application/json"}},
+					"bodyPatterns": [{"equalTo": "{}"}, {"matches": ".*items.*"}]
+				},
+				"response": {"status": 201, "headers": {"Vary": ["Accept", "Origin"]}, "jsonBody": {"id": 1}, "fixedDelayMilliseconds": 20}
+			}`,
+			wantMappings: []Mapping{
+				{
+					ID: "create-order",
+					Request: RequestMapping{
+						Method:  "POST",
+						Path:    CommonMatch{Patterns: []string{`^/orders(\?.*)?$`, `[?&]dryRun=[^&]*tr`, `[?&]tenant=(?:[a-z]+)(&|$)`}},
+						Headers: map[string]CommonMatch{"content-type": {Exact: "application/json"}},
+						Body:    BodyMatch{CommonMatch: CommonMatch{Exact: "{}", Patterns: []string{"^(?:.*items.*)$"}}},
+					},
+					Response: ResponseMapping{
+						StatusCode:    201,
+						Headers:       map[string]string{"Vary": "Accept, Origin"},
+						Body:          `{"id": 1}`,
+						ResponseDelay: Delay{Fixed: FixedDelay{Duration: Duration(20 * time.Millisecond)}},
+					},
+				},
+			},
+		},
+		{
+			name: "Should convert scenarios",
+			content: `{
+				"scenarioName": "cart",
+				"requiredScenarioState": "Started",
+				"newScenarioState": "full",
+				"request": {"method": "GET", "url": "/cart?page=1"},
+				"response": {"body": "empty"}
+			}`,
+			wantMappings: []Mapping{
+				{
+					Scenario: &ScenarioMapping{Name: "cart", StartingState: true, State: "Started", NewState: "full"},
+					Request:  RequestMapping{Method: "GET", Path: CommonMatch{Exact: "/cart?page=1"}},
+					Response: ResponseMapping{StatusCode: 200, Body: "empty"},
+				},
+			},
+		},
+		{
+			name: "Should report unsupported features",
+			content: `{
+				"mappings": [
+					{
+						"uuid": "b8f4",
+						"priority": 1,
+						"request": {
+							"method": "GET",
+							"urlPattern": "/users/.*",
+							"headers": {"X-Id": {"absent": true}, "Accept": {"equalTo": "text/plain", "caseInsensitive": true}},
+							"bodyPatterns": [{"equalToJson": {"a": 1}}],
+							"cookies": {"session": {"equalTo": "1"}}
+						},
+						"response": {"status": 200, "fault": "EMPTY_RESPONSE"}
+					},
+					{
+						"request": {"method": "ANY", "url": "/any"},
+						"response": {"status": 200}
+					}
+				]
+			}`,
+			wantMappings: []Mapping{
+				{
+					ID: "b8f4",
+					Request: RequestMapping{
+						Method:  "GET",
+						Path:    CommonMatch{Patterns: []string{"^(?:/users/.*)$"}},
+						Headers: map[string]CommonMatch{"accept": {Exact: "text/plain"}},
+						Body:    BodyMatch{CommonMatch: CommonMatch{Exact: `{"a": 1}`}},
+					},
+					Response: ResponseMapping{StatusCode: 200},
+				},
+			},
+			wantWarnings: []string{
+				"stub 'b8f4': 'priority' is not supported",
+				"stub 'b8f4': 'request.cookies' is not supported",
+				"stub 'b8f4': options of 'request.headers.Accept.equalTo' are not supported: caseInsensitive",
+				"stub 'b8f4': 'request.headers.X-Id' matcher is not supported: absent",
+				"stub 'b8f4': 'request.bodyPatterns[0].equalToJson' is matched as an exact string, whitespace and field order must match",
+				"stub 'b8f4': 'response.fault' is not supported",
+				"stub 1: requests with any method are not supported, the stub is skipped",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mappings, warnings, err := ConvertWireMock([]byte(tt.content))
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.wantMappings, mappings)
+			assert.Equal(t, tt.wantWarnings, warnings)
+		})
+	}
+}
+
+func TestLoadWireMockMappings(t *testing.T) {
+	regexCache := NewRegexCache()
+	jsonPathCache := NewJSONPathCache()
+	matcher := NewMatcher(regexCache, jsonPathCache)
+	scenarioHandler := NewScenarioHandler(matcher)
+	loader := NewLoader(regexCache, jsonPathCache, scenarioHandler)
+	loader.wireMock = true
+
+	mappings, err := loader.LoadMappings("testdata/wiremock/mapping", "testdata/wiremock/response")
+	require.NoError(t, err)
+
+	assert.Len(t, mappings["GET"], 2)
+	assert.Len(t, mappings["POST"], 1)
+
+	mapping, matched, _ := matcher.Match(Request{Method: "GET", Path: "/products/1?fields=name", Headers: map[string]string{"accept": "application/json"}}, mappings, nil)
+	require.True(t, matched)
+	assert.Equal(t, "get-product", mapping.ID)
+	assert.Equal(t, `{"id": 12345, "name": "Product"}`, mapping.Response.Body)
+	assert.Equal(t, Duration(150*time.Millisecond), mapping.Response.ResponseDelay.Fixed.Duration)
+
+	first, matched, _ := scenarioHandler.MatchScenario(Request{Method: "POST", Path: "/cart"})
+	require.True(t, matched)
+	second, matched, _ := scenarioHandler.MatchScenario(Request{Method: "POST", Path: "/cart"})
+	require.True(t, matched)
+	assert.Equal(t, 201, first.Response.StatusCode)
+	assert.Equal(t, 409, second.Response.StatusCode)
+}
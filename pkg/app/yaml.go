@@ -0,0 +1,187 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// IsYAMLFile reports whether the file should be decoded as YAML, based on its extension.
+func IsYAMLFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return true
+	default:
+		return false
+	}
+}
+
+// YAMLToJSON converts a YAML document to JSON, so it can be decoded with the same rules used for JSON files.
+func YAMLToJSON(content []byte) ([]byte, error) {
+	var value any
+	if err := yaml.Unmarshal(content, &value); err != nil {
+		return nil, err
+	}
+	return json.Marshal(stringKeys(value))
+}
+
+// stringKeys converts the maps decoded from YAML, which may have keys of any type, to maps with string keys.
+func stringKeys(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for k, e := range v {
+			v[k] = stringKeys(e)
+		}
+		return v
+	case map[any]any:
+		m := make(map[string]any, len(v))
+		for k, e := range v {
+			m[fmt.Sprint(k)] = stringKeys(e)
+		}
+		return m
+	case []any:
+		for i, e := range v {
+			v[i] = stringKeys(e)
+		}
+		return v
+	default:
+		return value
+	}
+}
+
+// decodeYAMLMappings decodes a YAML file holding a single mapping or a list of mappings.
+//
+// Each mapping is converted to JSON and decoded with the same rules used for JSON files,
+// so errors are mapped back to the line of the YAML node that caused them.
+func decodeYAMLMappings(path string, content []byte) ([]Mapping, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(content, &root); err != nil {
+		return nil, errors.Wrapf(err, "error decoding file '%s'", path)
+	}
+
+	if len(root.Content) == 0 {
+		return nil, errors.Errorf("error decoding file '%s': file is empty", path)
+	}
+
+	doc := root.Content[0]
+	nodes := []*yaml.Node{doc}
+	if doc.Kind == yaml.SequenceNode {
+		nodes = doc.Content
+	}
+
+	mappings := make([]Mapping, 0, len(nodes))
+	for _, node := range nodes {
+		m, err := decodeYAMLMapping(node)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error decoding file '%s'", path)
+		}
+		mappings = append(mappings, m)
+	}
+
+	return mappings, nil
+}
+
+func decodeYAMLMapping(node *yaml.Node) (Mapping, error) {
+	var m Mapping
+
+	if node.Kind != yaml.MappingNode {
+		return m, errors.Errorf("line %d: mapping must be an object", node.Line)
+	}
+
+	var value any
+	if err := node.Decode(&value); err != nil {
+		return m, err
+	}
+
+	content, err := json.Marshal(stringKeys(value))
+	if err != nil {
+		return m, errors.Wrapf(err, "line %d", node.Line)
+	}
+
+	if err := json.Unmarshal(content, &m); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return m, errors.Errorf("line %d: cannot use %s as %s in field '%s'", fieldLine(node, typeErr.Field), typeErr.Value, typeErr.Type, typeErr.Field)
+		}
+		return m, errors.Wrapf(err, "line %d", errorNode(node, func(v any) any { return v }).Line)
+	}
+
+	return m, nil
+}
+
+// errorNode returns the deepest node that still fails to decode when it is the only field of the mapping,
+// which gives the line of errors that don't tell the field that caused them, like the ones of custom unmarshalers.
+//
+// wrap nests the value of a node in the fields and lists of its parents.
+func errorNode(node *yaml.Node, wrap func(any) any) *yaml.Node {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, child := node.Content[i].Value, node.Content[i+1]
+			wrapChild := func(v any) any { return wrap(map[string]any{key: v}) }
+			if failsAlone(child, wrapChild) {
+				return errorNode(child, wrapChild)
+			}
+		}
+	case yaml.SequenceNode:
+		for _, child := range node.Content {
+			wrapChild := func(v any) any { return wrap([]any{v}) }
+			if failsAlone(child, wrapChild) {
+				return errorNode(child, wrapChild)
+			}
+		}
+	}
+
+	return node
+}
+
+func failsAlone(node *yaml.Node, wrap func(any) any) bool {
+	var value any
+	if err := node.Decode(&value); err != nil {
+		return true
+	}
+
+	content, err := json.Marshal(wrap(stringKeys(value)))
+	if err != nil {
+		return true
+	}
+
+	var m Mapping
+	return json.Unmarshal(content, &m) != nil
+}
+
+// fieldLine returns the line of the node found by following the dotted field path,
+// or the line of the deepest node found along the way.
+func fieldLine(node *yaml.Node, field string) int {
+	line := node.Line
+	if field == "" {
+		return line
+	}
+
+	current := node
+	for _, key := range strings.Split(field, ".") {
+		if current.Kind != yaml.MappingNode {
+			break
+		}
+
+		var next *yaml.Node
+		for i := 0; i+1 < len(current.Content); i += 2 {
+			if current.Content[i].Value == key {
+				next = current.Content[i+1]
+				break
+			}
+		}
+		if next == nil {
+			break
+		}
+
+		current = next
+		line = current.Line
+	}
+
+	return line
+}